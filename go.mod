@@ -3,8 +3,11 @@ module github.com/slok/kubewebhook/v2
 go 1.15
 
 require (
+	github.com/evanphx/json-patch v4.9.0+incompatible
+	github.com/go-logr/logr v0.2.0
 	github.com/kr/text v0.2.0 // indirect
 	github.com/niemeyer/pretty v0.0.0-20200227124842-a10e7caefd8e // indirect
+	github.com/opentracing/opentracing-go v1.2.0
 	github.com/prometheus/client_golang v1.9.0
 	github.com/sirupsen/logrus v1.7.0
 	github.com/stretchr/testify v1.6.1
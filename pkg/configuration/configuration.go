@@ -0,0 +1,249 @@
+// Package configuration has helpers to create the Kubernetes webhook configurations
+// (`MutatingWebhookConfiguration` and `ValidatingWebhookConfiguration`) that register
+// the webhooks on the apiserver, so they can be managed programmatically instead of
+// maintaining YAML manifests.
+package configuration
+
+import (
+	"fmt"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WebhookConfig is the configuration of a webhook registered on the apiserver.
+type WebhookConfig struct {
+	// Name is the name of the webhook configuration and the webhook, it must be fully
+	// qualified (e.g `pod-annotate.webhook.example.io`).
+	Name string
+	// Service is the Kubernetes service reference of the webhook, `Service` or `URL` are required.
+	Service *admissionregistrationv1.ServiceReference
+	// URL is the URL of the webhook when it's running outside the cluster, `Service` or `URL` are required.
+	URL string
+	// CABundle is the PEM encoded CA bundle used to validate the webhook server certificate.
+	CABundle []byte
+	// Rules are the resources and operations that will be sent to the webhook.
+	Rules []admissionregistrationv1.RuleWithOperations
+	// FailurePolicy is the policy used when the webhook fails, by default `Fail`.
+	FailurePolicy admissionregistrationv1.FailurePolicyType
+	// SideEffects tells if the webhook has side effects, by default `None`.
+	SideEffects admissionregistrationv1.SideEffectClass
+	// AdmissionReviewVersions are the accepted admission review versions, by default `v1` and `v1beta1`.
+	AdmissionReviewVersions []string
+	// TimeoutSeconds is the timeout for the webhook call, if not set it will use the apiserver default.
+	TimeoutSeconds int32
+	// NamespaceSelector selects the namespaces that will be sent to the webhook.
+	NamespaceSelector *metav1.LabelSelector
+	// ObjectSelector selects the objects that will be sent to the webhook.
+	ObjectSelector *metav1.LabelSelector
+	// Path is the HTTP path of the webhook on the service (`clientConfig.service.path`), it can
+	// only be used with `Service`.
+	Path string
+	// Webhooks are the webhooks of the configuration, use it to register multiple webhooks (e.g
+	// one per path of the same server) on the same configuration, `Name` will be only the name
+	// of the configuration. The settings not set on the webhooks will use the configuration ones.
+	// If not set, the configuration will have a single webhook with the configuration settings.
+	Webhooks []Webhook
+}
+
+// Webhook is a webhook of a webhook configuration with multiple webhooks.
+type Webhook struct {
+	// Name is the name of the webhook, it must be fully qualified (e.g `pod-annotate.webhook.example.io`).
+	Name string
+	// Path is the HTTP path of the webhook on the service (`clientConfig.service.path`).
+	Path string
+	// Rules are the resources and operations that will be sent to the webhook.
+	Rules []admissionregistrationv1.RuleWithOperations
+	// FailurePolicy is the policy used when the webhook fails.
+	FailurePolicy admissionregistrationv1.FailurePolicyType
+	// SideEffects tells if the webhook has side effects.
+	SideEffects admissionregistrationv1.SideEffectClass
+	// TimeoutSeconds is the timeout for the webhook call.
+	TimeoutSeconds int32
+	// NamespaceSelector selects the namespaces that will be sent to the webhook.
+	NamespaceSelector *metav1.LabelSelector
+	// ObjectSelector selects the objects that will be sent to the webhook.
+	ObjectSelector *metav1.LabelSelector
+}
+
+func (c *WebhookConfig) defaults() error {
+	if c.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	if c.Service == nil && c.URL == "" {
+		return fmt.Errorf("service or URL is required")
+	}
+
+	if c.Service != nil && c.URL != "" {
+		return fmt.Errorf("service and URL can't be used at the same time")
+	}
+
+	if c.FailurePolicy == "" {
+		c.FailurePolicy = admissionregistrationv1.Fail
+	}
+
+	if c.SideEffects == "" {
+		c.SideEffects = admissionregistrationv1.SideEffectClassNone
+	}
+
+	if len(c.AdmissionReviewVersions) == 0 {
+		c.AdmissionReviewVersions = []string{"v1", "v1beta1"}
+	}
+
+	// Don't modify the received webhooks when setting the defaults.
+	whs := append([]Webhook{}, c.Webhooks...)
+	if len(whs) == 0 {
+		whs = []Webhook{{Name: c.Name}}
+	}
+
+	for i, wh := range whs {
+		if wh.Name == "" {
+			return fmt.Errorf("webhook name is required")
+		}
+
+		if wh.Path == "" {
+			wh.Path = c.Path
+		}
+
+		if wh.Path != "" && c.Service == nil {
+			return fmt.Errorf("%q webhook path can only be used with service", wh.Name)
+		}
+
+		if len(wh.Rules) == 0 {
+			wh.Rules = c.Rules
+		}
+
+		if len(wh.Rules) == 0 {
+			return fmt.Errorf("%q webhook requires at least one rule", wh.Name)
+		}
+
+		if wh.FailurePolicy == "" {
+			wh.FailurePolicy = c.FailurePolicy
+		}
+
+		if wh.SideEffects == "" {
+			wh.SideEffects = c.SideEffects
+		}
+
+		if wh.TimeoutSeconds == 0 {
+			wh.TimeoutSeconds = c.TimeoutSeconds
+		}
+
+		if wh.NamespaceSelector == nil {
+			wh.NamespaceSelector = c.NamespaceSelector
+		}
+
+		if wh.ObjectSelector == nil {
+			wh.ObjectSelector = c.ObjectSelector
+		}
+
+		whs[i] = wh
+	}
+	c.Webhooks = whs
+
+	return nil
+}
+
+func (c WebhookConfig) clientConfig(wh Webhook) admissionregistrationv1.WebhookClientConfig {
+	cc := admissionregistrationv1.WebhookClientConfig{
+		CABundle: c.CABundle,
+	}
+
+	if c.Service != nil {
+		// Copy the service, each webhook can have a different path.
+		svc := *c.Service
+		if wh.Path != "" {
+			path := wh.Path
+			svc.Path = &path
+		}
+		cc.Service = &svc
+	}
+
+	if c.URL != "" {
+		url := c.URL
+		cc.URL = &url
+	}
+
+	return cc
+}
+
+// admissionReviewVersions returns a new slice for each webhook, this way the returned
+// webhooks don't share the same backing array.
+func (c WebhookConfig) admissionReviewVersions() []string {
+	return append([]string{}, c.AdmissionReviewVersions...)
+}
+
+func timeoutSeconds(wh Webhook) *int32 {
+	if wh.TimeoutSeconds == 0 {
+		return nil
+	}
+	t := wh.TimeoutSeconds
+	return &t
+}
+
+// NewMutatingWebhookConfiguration returns a new Kubernetes mutating webhook configuration.
+func NewMutatingWebhookConfiguration(config WebhookConfig) (*admissionregistrationv1.MutatingWebhookConfiguration, error) {
+	err := config.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	whs := make([]admissionregistrationv1.MutatingWebhook, 0, len(config.Webhooks))
+	for _, wh := range config.Webhooks {
+		wh := wh
+		whs = append(whs, admissionregistrationv1.MutatingWebhook{
+			Name:                    wh.Name,
+			ClientConfig:            config.clientConfig(wh),
+			Rules:                   wh.Rules,
+			FailurePolicy:           &wh.FailurePolicy,
+			SideEffects:             &wh.SideEffects,
+			AdmissionReviewVersions: config.admissionReviewVersions(),
+			TimeoutSeconds:          timeoutSeconds(wh),
+			NamespaceSelector:       wh.NamespaceSelector,
+			ObjectSelector:          wh.ObjectSelector,
+		})
+	}
+
+	return &admissionregistrationv1.MutatingWebhookConfiguration{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "admissionregistration.k8s.io/v1",
+			Kind:       "MutatingWebhookConfiguration",
+		},
+		ObjectMeta: metav1.ObjectMeta{Name: config.Name},
+		Webhooks:   whs,
+	}, nil
+}
+
+// NewValidatingWebhookConfiguration returns a new Kubernetes validating webhook configuration.
+func NewValidatingWebhookConfiguration(config WebhookConfig) (*admissionregistrationv1.ValidatingWebhookConfiguration, error) {
+	err := config.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	whs := make([]admissionregistrationv1.ValidatingWebhook, 0, len(config.Webhooks))
+	for _, wh := range config.Webhooks {
+		wh := wh
+		whs = append(whs, admissionregistrationv1.ValidatingWebhook{
+			Name:                    wh.Name,
+			ClientConfig:            config.clientConfig(wh),
+			Rules:                   wh.Rules,
+			FailurePolicy:           &wh.FailurePolicy,
+			SideEffects:             &wh.SideEffects,
+			AdmissionReviewVersions: config.admissionReviewVersions(),
+			TimeoutSeconds:          timeoutSeconds(wh),
+			NamespaceSelector:       wh.NamespaceSelector,
+			ObjectSelector:          wh.ObjectSelector,
+		})
+	}
+
+	return &admissionregistrationv1.ValidatingWebhookConfiguration{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "admissionregistration.k8s.io/v1",
+			Kind:       "ValidatingWebhookConfiguration",
+		},
+		ObjectMeta: metav1.ObjectMeta{Name: config.Name},
+		Webhooks:   whs,
+	}, nil
+}
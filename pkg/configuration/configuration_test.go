@@ -0,0 +1,390 @@
+package configuration_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/slok/kubewebhook/v2/pkg/configuration"
+)
+
+var (
+	testRules = []admissionregistrationv1.RuleWithOperations{
+		{
+			Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create},
+			Rule: admissionregistrationv1.Rule{
+				APIGroups:   []string{""},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"pods"},
+			},
+		},
+	}
+	testService = &admissionregistrationv1.ServiceReference{
+		Namespace: "test-ns",
+		Name:      "test-svc",
+	}
+	fail       = admissionregistrationv1.Fail
+	ignore     = admissionregistrationv1.Ignore
+	none       = admissionregistrationv1.SideEffectClassNone
+	url        = "https://webhook.example.io/mutate"
+	timeoutSec = int32(5)
+	podPath    = "/pod"
+	allPath    = "/all"
+	port       = int32(8443)
+	noneDryRun = admissionregistrationv1.SideEffectClassNoneOnDryRun
+	allRules   = []admissionregistrationv1.RuleWithOperations{
+		{
+			Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.OperationAll},
+			Rule: admissionregistrationv1.Rule{
+				APIGroups:   []string{"*"},
+				APIVersions: []string{"*"},
+				Resources:   []string{"*"},
+			},
+		},
+	}
+)
+
+func TestNewMutatingWebhookConfiguration(t *testing.T) {
+	tests := map[string]struct {
+		config configuration.WebhookConfig
+		expWHC *admissionregistrationv1.MutatingWebhookConfiguration
+		expErr bool
+	}{
+		"Missing name should fail.": {
+			config: configuration.WebhookConfig{Service: testService, Rules: testRules},
+			expErr: true,
+		},
+
+		"Missing service and URL should fail.": {
+			config: configuration.WebhookConfig{Name: "test.example.io", Rules: testRules},
+			expErr: true,
+		},
+
+		"Using service and URL at the same time should fail.": {
+			config: configuration.WebhookConfig{Name: "test.example.io", Service: testService, URL: url, Rules: testRules},
+			expErr: true,
+		},
+
+		"Missing rules should fail.": {
+			config: configuration.WebhookConfig{Name: "test.example.io", Service: testService},
+			expErr: true,
+		},
+
+		"A service webhook should use the defaults.": {
+			config: configuration.WebhookConfig{
+				Name:     "test.example.io",
+				Service:  testService,
+				CABundle: []byte("test-ca"),
+				Rules:    testRules,
+			},
+			expWHC: &admissionregistrationv1.MutatingWebhookConfiguration{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "admissionregistration.k8s.io/v1",
+					Kind:       "MutatingWebhookConfiguration",
+				},
+				ObjectMeta: metav1.ObjectMeta{Name: "test.example.io"},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{
+					{
+						Name: "test.example.io",
+						ClientConfig: admissionregistrationv1.WebhookClientConfig{
+							Service:  testService,
+							CABundle: []byte("test-ca"),
+						},
+						Rules:                   testRules,
+						FailurePolicy:           &fail,
+						SideEffects:             &none,
+						AdmissionReviewVersions: []string{"v1", "v1beta1"},
+					},
+				},
+			},
+		},
+
+		"A customized URL webhook should set the custom settings.": {
+			config: configuration.WebhookConfig{
+				Name:                    "test.example.io",
+				URL:                     url,
+				Rules:                   testRules,
+				FailurePolicy:           admissionregistrationv1.Ignore,
+				AdmissionReviewVersions: []string{"v1"},
+				TimeoutSeconds:          5,
+				ObjectSelector:          &metav1.LabelSelector{MatchLabels: map[string]string{"k": "v"}},
+			},
+			expWHC: &admissionregistrationv1.MutatingWebhookConfiguration{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "admissionregistration.k8s.io/v1",
+					Kind:       "MutatingWebhookConfiguration",
+				},
+				ObjectMeta: metav1.ObjectMeta{Name: "test.example.io"},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{
+					{
+						Name: "test.example.io",
+						ClientConfig: admissionregistrationv1.WebhookClientConfig{
+							URL: &url,
+						},
+						Rules:                   testRules,
+						FailurePolicy:           &ignore,
+						SideEffects:             &none,
+						AdmissionReviewVersions: []string{"v1"},
+						TimeoutSeconds:          &timeoutSec,
+						ObjectSelector:          &metav1.LabelSelector{MatchLabels: map[string]string{"k": "v"}},
+					},
+				},
+			},
+		},
+
+		"Using a path with URL should fail.": {
+			config: configuration.WebhookConfig{Name: "test.example.io", URL: url, Path: podPath, Rules: testRules},
+			expErr: true,
+		},
+
+		"Missing webhook name on multiple webhooks should fail.": {
+			config: configuration.WebhookConfig{
+				Name:     "test",
+				Service:  testService,
+				Webhooks: []configuration.Webhook{{Path: podPath, Rules: testRules}},
+			},
+			expErr: true,
+		},
+
+		"Missing webhook rules on multiple webhooks should fail.": {
+			config: configuration.WebhookConfig{
+				Name:     "test",
+				Service:  testService,
+				Webhooks: []configuration.Webhook{{Name: "pod.example.io", Path: podPath}},
+			},
+			expErr: true,
+		},
+
+		"A service webhook with a path should set the path on the service.": {
+			config: configuration.WebhookConfig{
+				Name:    "test.example.io",
+				Service: &admissionregistrationv1.ServiceReference{Namespace: "test-ns", Name: "test-svc", Port: &port},
+				Path:    podPath,
+				Rules:   testRules,
+			},
+			expWHC: &admissionregistrationv1.MutatingWebhookConfiguration{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "admissionregistration.k8s.io/v1",
+					Kind:       "MutatingWebhookConfiguration",
+				},
+				ObjectMeta: metav1.ObjectMeta{Name: "test.example.io"},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{
+					{
+						Name: "test.example.io",
+						ClientConfig: admissionregistrationv1.WebhookClientConfig{
+							Service: &admissionregistrationv1.ServiceReference{Namespace: "test-ns", Name: "test-svc", Port: &port, Path: &podPath},
+						},
+						Rules:                   testRules,
+						FailurePolicy:           &fail,
+						SideEffects:             &none,
+						AdmissionReviewVersions: []string{"v1", "v1beta1"},
+					},
+				},
+			},
+		},
+
+		"Multiple webhooks should be set on the configuration using the configuration settings as defaults.": {
+			config: configuration.WebhookConfig{
+				Name:           "test",
+				Service:        testService,
+				CABundle:       []byte("test-ca"),
+				Rules:          testRules,
+				TimeoutSeconds: 5,
+				Webhooks: []configuration.Webhook{
+					{Name: "pod.example.io", Path: podPath},
+					{
+						Name:              "all.example.io",
+						Path:              allPath,
+						Rules:             allRules,
+						FailurePolicy:     admissionregistrationv1.Ignore,
+						SideEffects:       admissionregistrationv1.SideEffectClassNoneOnDryRun,
+						NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"webhook": "enabled"}},
+						ObjectSelector:    &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}},
+					},
+				},
+			},
+			expWHC: &admissionregistrationv1.MutatingWebhookConfiguration{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "admissionregistration.k8s.io/v1",
+					Kind:       "MutatingWebhookConfiguration",
+				},
+				ObjectMeta: metav1.ObjectMeta{Name: "test"},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{
+					{
+						Name: "pod.example.io",
+						ClientConfig: admissionregistrationv1.WebhookClientConfig{
+							Service:  &admissionregistrationv1.ServiceReference{Namespace: "test-ns", Name: "test-svc", Path: &podPath},
+							CABundle: []byte("test-ca"),
+						},
+						Rules:                   testRules,
+						FailurePolicy:           &fail,
+						SideEffects:             &none,
+						AdmissionReviewVersions: []string{"v1", "v1beta1"},
+						TimeoutSeconds:          &timeoutSec,
+					},
+					{
+						Name: "all.example.io",
+						ClientConfig: admissionregistrationv1.WebhookClientConfig{
+							Service:  &admissionregistrationv1.ServiceReference{Namespace: "test-ns", Name: "test-svc", Path: &allPath},
+							CABundle: []byte("test-ca"),
+						},
+						Rules:                   allRules,
+						FailurePolicy:           &ignore,
+						SideEffects:             &noneDryRun,
+						AdmissionReviewVersions: []string{"v1", "v1beta1"},
+						TimeoutSeconds:          &timeoutSec,
+						NamespaceSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"webhook": "enabled"}},
+						ObjectSelector:          &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}},
+					},
+				},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			gotWHC, err := configuration.NewMutatingWebhookConfiguration(test.config)
+
+			if test.expErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+				assert.Equal(test.expWHC, gotWHC)
+			}
+		})
+	}
+}
+
+func TestNewValidatingWebhookConfiguration(t *testing.T) {
+	tests := map[string]struct {
+		config configuration.WebhookConfig
+		expWHC *admissionregistrationv1.ValidatingWebhookConfiguration
+		expErr bool
+	}{
+		"Missing name should fail.": {
+			config: configuration.WebhookConfig{Service: testService, Rules: testRules},
+			expErr: true,
+		},
+
+		"A service webhook should use the defaults.": {
+			config: configuration.WebhookConfig{
+				Name:     "test.example.io",
+				Service:  testService,
+				CABundle: []byte("test-ca"),
+				Rules:    testRules,
+			},
+			expWHC: &admissionregistrationv1.ValidatingWebhookConfiguration{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "admissionregistration.k8s.io/v1",
+					Kind:       "ValidatingWebhookConfiguration",
+				},
+				ObjectMeta: metav1.ObjectMeta{Name: "test.example.io"},
+				Webhooks: []admissionregistrationv1.ValidatingWebhook{
+					{
+						Name: "test.example.io",
+						ClientConfig: admissionregistrationv1.WebhookClientConfig{
+							Service:  testService,
+							CABundle: []byte("test-ca"),
+						},
+						Rules:                   testRules,
+						FailurePolicy:           &fail,
+						SideEffects:             &none,
+						AdmissionReviewVersions: []string{"v1", "v1beta1"},
+					},
+				},
+			},
+		},
+
+		"Multiple webhooks should be set on the configuration.": {
+			config: configuration.WebhookConfig{
+				Name:    "test",
+				Service: testService,
+				Webhooks: []configuration.Webhook{
+					{Name: "pod.example.io", Path: podPath, Rules: testRules},
+					{Name: "all.example.io", Path: allPath, Rules: allRules, FailurePolicy: admissionregistrationv1.Ignore},
+				},
+			},
+			expWHC: &admissionregistrationv1.ValidatingWebhookConfiguration{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "admissionregistration.k8s.io/v1",
+					Kind:       "ValidatingWebhookConfiguration",
+				},
+				ObjectMeta: metav1.ObjectMeta{Name: "test"},
+				Webhooks: []admissionregistrationv1.ValidatingWebhook{
+					{
+						Name: "pod.example.io",
+						ClientConfig: admissionregistrationv1.WebhookClientConfig{
+							Service: &admissionregistrationv1.ServiceReference{Namespace: "test-ns", Name: "test-svc", Path: &podPath},
+						},
+						Rules:                   testRules,
+						FailurePolicy:           &fail,
+						SideEffects:             &none,
+						AdmissionReviewVersions: []string{"v1", "v1beta1"},
+					},
+					{
+						Name: "all.example.io",
+						ClientConfig: admissionregistrationv1.WebhookClientConfig{
+							Service: &admissionregistrationv1.ServiceReference{Namespace: "test-ns", Name: "test-svc", Path: &allPath},
+						},
+						Rules:                   allRules,
+						FailurePolicy:           &ignore,
+						SideEffects:             &none,
+						AdmissionReviewVersions: []string{"v1", "v1beta1"},
+					},
+				},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			gotWHC, err := configuration.NewValidatingWebhookConfiguration(test.config)
+
+			if test.expErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+				assert.Equal(test.expWHC, gotWHC)
+			}
+		})
+	}
+}
+
+func TestWebhookConfigurationDontShareData(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	config := configuration.WebhookConfig{
+		Name:    "test",
+		Service: testService,
+		Webhooks: []configuration.Webhook{
+			{Name: "pod.example.io", Path: podPath, Rules: testRules},
+			{Name: "all.example.io", Path: allPath, Rules: allRules},
+		},
+	}
+
+	whc1, err := configuration.NewMutatingWebhookConfiguration(config)
+	require.NoError(err)
+	whc2, err := configuration.NewMutatingWebhookConfiguration(config)
+	require.NoError(err)
+
+	// Modifying a returned webhook should not modify the other webhooks, configurations or the received data.
+	whc1.Webhooks[0].AdmissionReviewVersions[0] = "modified"
+	*whc1.Webhooks[0].ClientConfig.Service.Path = "/modified"
+	whc1.Webhooks[0].ClientConfig.Service.Name = "modified"
+
+	assert.Equal([]string{"v1", "v1beta1"}, whc1.Webhooks[1].AdmissionReviewVersions)
+	assert.Equal([]string{"v1", "v1beta1"}, whc2.Webhooks[0].AdmissionReviewVersions)
+	assert.Equal("/pod", *whc2.Webhooks[0].ClientConfig.Service.Path)
+	assert.Equal("test-svc", whc1.Webhooks[1].ClientConfig.Service.Name)
+	assert.Equal("/pod", config.Webhooks[0].Path)
+	assert.Nil(testService.Path)
+	assert.Equal("test-svc", testService.Name)
+}
@@ -10,6 +10,7 @@ import (
 
 	whhttp "github.com/slok/kubewebhook/v2/pkg/http"
 	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/webhook"
 	"github.com/slok/kubewebhook/v2/pkg/webhook/mutating"
 	"github.com/slok/kubewebhook/v2/pkg/webhook/validating"
 )
@@ -86,3 +87,70 @@ func ExampleHandlerFor_serveMultipleWebhooks() {
 	mux.Handle("/mutate-pod", mwhHandler)
 	_ = http.ListenAndServeTLS(":8080", "file.cert", "file.key", mux)
 }
+
+// ServeMultipleWebhooks shows how to serve multiple webhooks in the same server with a single handler.
+func ExampleMuxFor_serveMultipleWebhooks() {
+	// Create webhooks (don't check error).
+	vwh, _ := validating.NewWebhook(validating.WebhookConfig{
+		ID:        "validatingServeWebhook",
+		Obj:       &corev1.Pod{},
+		Validator: validating.NoopValidator,
+	})
+	mwh, _ := mutating.NewWebhook(mutating.WebhookConfig{
+		ID:      "mutatingServeWebhook",
+		Obj:     &corev1.Pod{},
+		Mutator: mutating.NoopMutator,
+	})
+
+	// Get the handler that serves each webhook on its path.
+	h, _ := whhttp.MuxFor(map[string]webhook.Webhook{
+		"/mutate":   mwh,
+		"/validate": vwh,
+	}, whhttp.HandlerConfig{})
+
+	// Serve the webhooks with the health checks on the same server.
+	mux := http.NewServeMux()
+	mux.Handle("/", h)
+	mux.Handle("/healthz", whhttp.HealthHandler())
+	readiness := whhttp.NewReadinessGate()
+	mux.Handle("/readyz", readiness)
+	readiness.SetReady(true)
+
+	// The apiserver only calls webhooks using HTTPS, the certificate must be valid for the
+	// webhook service DNS name (e.g `my-webhook.my-ns.svc`) and signed by the CA set on the
+	// webhook configuration `caBundle`.
+	_ = http.ListenAndServeTLS(":8080", "file.cert", "file.key", mux)
+}
+
+// ReloadCertificates shows how to serve a webhook reloading the rotated TLS certificates (e.g cert-manager)
+// without restarting the server.
+func ExampleCertReloader_reloadCertificates() {
+	wh, _ := mutating.NewWebhook(mutating.WebhookConfig{
+		ID:      "reloadCertificatesWebhook",
+		Obj:     &corev1.Pod{},
+		Mutator: mutating.NoopMutator,
+	})
+	whHandler, _ := whhttp.HandlerFor(whhttp.HandlerConfig{Webhook: wh})
+
+	// Create the certificate reloader (don't check error).
+	certReloader, _ := whhttp.NewCertReloader(whhttp.CertReloaderConfig{
+		CertFile: "/etc/webhook/certs/tls.crt",
+		KeyFile:  "/etc/webhook/certs/tls.key",
+	})
+
+	// Serve the webhook with the probes, ready once the certificates have been loaded.
+	readiness := whhttp.NewReadinessGate()
+	mux := http.NewServeMux()
+	mux.Handle("/webhook", whHandler)
+	mux.Handle("/healthz", whhttp.HealthHandler())
+	mux.Handle("/readyz", readiness)
+	readiness.SetReady(true)
+
+	// The certificates are obtained from the TLS config, so we don't need to set them when serving.
+	server := &http.Server{
+		Addr:      ":8080",
+		Handler:   mux,
+		TLSConfig: certReloader.TLSConfig(),
+	}
+	_ = server.ListenAndServeTLS("", "")
+}
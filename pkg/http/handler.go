@@ -1,11 +1,15 @@
 package http
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"mime"
 	"net/http"
+	"runtime/debug"
 	"strings"
 	"time"
 
@@ -18,6 +22,7 @@ import (
 
 	"github.com/slok/kubewebhook/v2/pkg/log"
 	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/tracing"
 	"github.com/slok/kubewebhook/v2/pkg/webhook"
 )
 
@@ -32,6 +37,8 @@ var (
 	deserializer = codecs.UniversalDeserializer()
 )
 
+const defaultMaxRequestBodyBytes = 3 * 1024 * 1024
+
 // MustHandlerFor it's the same as HandleFor but will panic instead of returning
 // a error.
 func MustHandlerFor(config HandlerConfig) http.Handler {
@@ -46,6 +53,26 @@ func MustHandlerFor(config HandlerConfig) http.Handler {
 type HandlerConfig struct {
 	Webhook webhook.Webhook
 	Logger  log.Logger
+	// Tracer will trace the handled requests, continuing the traces propagated
+	// on the request (if any). By default it will not trace.
+	Tracer tracing.Tracer
+	// MaxRequestBodyBytes is the max size of the request body, bigger request bodies
+	// will be rejected. By default 3MiB.
+	MaxRequestBodyBytes int64
+	// Timeout is the max duration of the webhook review, the context of the review will be
+	// cancelled when the timeout is reached. Normally this should be lower than the webhook
+	// configuration `timeoutSeconds`. By default it will not have a timeout.
+	Timeout time.Duration
+	// DisablePanicRecovery will disable the recovery of the panics on the webhook reviews. By default
+	// the panics are recovered and returned as an error admission response, instead of crashing the
+	// request and returning a connection reset to the apiserver.
+	//
+	// Mutating and validating webhooks recover the mutator and validator panics by themselves (e.g to
+	// be handled by fail open webhooks), use their `DisablePanicRecovery` to let the panics reach the handler.
+	DisablePanicRecovery bool
+	// IndentResponses will indent the JSON of the admission review responses, this is
+	// useful for debugging or golden files. By default the responses are compact.
+	IndentResponses bool
 }
 
 func (c *HandlerConfig) defaults() error {
@@ -56,13 +83,32 @@ func (c *HandlerConfig) defaults() error {
 	if c.Logger == nil {
 		c.Logger = log.Noop
 	}
-	c.Logger = c.Logger.WithValues(log.Kv{"svc": "http.Handler"})
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "http.Handler", "webhook-id": c.Webhook.ID()})
+
+	if c.Tracer == nil {
+		c.Tracer = tracing.Noop
+	}
+
+	if c.MaxRequestBodyBytes == 0 {
+		c.MaxRequestBodyBytes = defaultMaxRequestBodyBytes
+	}
+
+	if c.MaxRequestBodyBytes < 0 {
+		return fmt.Errorf("max request body bytes can't be negative")
+	}
+
+	if c.Timeout < 0 {
+		return fmt.Errorf("timeout can't be negative")
+	}
 
 	return nil
 }
 
 // HandlerFor returns a new http.Handler ready to handle admission reviews using a
 // a webhook.
+//
+// The handler is agnostic of the admission review version, it will detect the received
+// version (`v1beta1` or `v1`) and respond with an admission review of the same version.
 func HandlerFor(config HandlerConfig) (http.Handler, error) {
 	err := config.defaults()
 	if err != nil {
@@ -70,25 +116,68 @@ func HandlerFor(config HandlerConfig) (http.Handler, error) {
 	}
 
 	return handler{
-		webhook: config.Webhook,
-		logger:  config.Logger}, nil
+		webhook:             config.Webhook,
+		logger:              config.Logger,
+		tracer:              config.Tracer,
+		maxRequestBodyBytes: config.MaxRequestBodyBytes,
+		timeout:             config.Timeout,
+		recoverPanics:       !config.DisablePanicRecovery,
+		indentResponses:     config.IndentResponses,
+	}, nil
 }
 
 type handler struct {
-	webhook webhook.Webhook
-	logger  log.Logger
+	webhook             webhook.Webhook
+	logger              log.Logger
+	tracer              tracing.Tracer
+	maxRequestBodyBytes int64
+	timeout             time.Duration
+	recoverPanics       bool
+	indentResponses     bool
 }
 
-func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+func (h handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	// Record the response status code and the review error, to end the trace as failed on errors.
+	w := &statusResponseWriter{ResponseWriter: rw, statusCode: http.StatusOK}
+	var reviewErr error
+	ctx := h.tracer.NewHTTPTrace(r, "http.Handler")
+	defer func(ctx context.Context) { h.tracer.EndTrace(ctx, httpTraceError(w.statusCode, reviewErr)) }(ctx)
 	t0 := time.Now()
 
-	// Get webhook body with the admission review.
+	// Admission reviews are always sent using POST.
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		h.logger.Errorf("method %q not allowed", r.Method)
+		return
+	}
+
+	// Admission reviews are JSON, don't allow other content types.
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil || mediaType != "application/json" {
+			http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+			h.logger.Errorf("content type %q not supported", ct)
+			return
+		}
+	}
+
+	// Get webhook body with the admission review, limiting the size so big bodies
+	// are rejected before being fully read (this will also close the connection).
 	var body []byte
 	if r.Body != nil {
-		if data, err := ioutil.ReadAll(r.Body); err == nil {
-			body = data
+		data, err := ioutil.ReadAll(http.MaxBytesReader(rw, r.Body, h.maxRequestBodyBytes))
+		if err != nil {
+			if isRequestBodyTooLargeError(err) {
+				http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+				h.logger.Warningf("request body too large, max allowed size is %d bytes", h.maxRequestBodyBytes)
+				return
+			}
+
+			http.Error(w, "could not read request body", http.StatusBadRequest)
+			h.logger.Errorf("could not read request body: %s", err)
+			return
 		}
+		body = data
 	}
 	if len(body) == 0 {
 		http.Error(w, "no body found", http.StatusBadRequest)
@@ -98,6 +187,24 @@ func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	ar, err := h.requestBodyToModelReview(body)
 	if err != nil {
+		// Admission reviews without request can't be reviewed, but we know the admission review
+		// version, so we can return a valid admission review error response.
+		if errors.Is(err, errMissingRequest) {
+			reviewErr = err
+			h.logger.Errorf("could not parse body to model review: %s", err)
+			h.writeBadRequestResponse(w, *ar, errMissingRequest.Error())
+			return
+		}
+
+		// If we know that is an admission review, although invalid, respond with a valid
+		// admission review error response using the information we have (e.g UID).
+		if ar, ok := partialRequestBodyToModelReview(body); ok {
+			reviewErr = err
+			h.logger.Errorf("could not parse body to model review: %s", err)
+			h.writeBadRequestResponse(w, *ar, "could not decode the admission review from the request")
+			return
+		}
+
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		h.logger.Errorf("could not parse body to model review: %s", err)
 		return
@@ -125,9 +232,27 @@ func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// | Validating not allowed | 200                   | 400         | Failure       | Custom message |
 	// | Mutating mutation      | 200                   | -           | -             | -              |
 	// | Mutating no mutation   | 200                   | -           | -             | -              |
-	// | Err                    | 500                   | -           | Failure       | Err string     |
-	admissionResp, err := h.webhook.Review(ctx, *ar)
+	// | Status Err             | 200                   | Custom code | Failure       | Err message    |
+	// | Err                    | 500                   | 500         | Failure       | Err string     |
+	reviewCtx := ctx
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		reviewCtx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+
+	admissionResp, err := h.review(reviewCtx, logger, *ar)
 	if err != nil {
+		reviewErr = err
+
+		// Status errors are not unexpected errors, they are a controlled way of
+		// denying the admission review, so the apiserver should receive them.
+		code := http.StatusInternalServerError
+		var statusErr *webhook.StatusError
+		if errors.As(err, &statusErr) {
+			code = http.StatusOK
+		}
+
 		errResp, err := h.errorToJSON(*ar, err)
 		if err != nil {
 			msg := fmt.Sprintf("could not marshall status error on admission response: %v", err)
@@ -136,7 +261,8 @@ func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		w.WriteHeader(http.StatusInternalServerError)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
 		if _, err := w.Write(errResp); err != nil {
 			msg := fmt.Sprintf("could not write response: %v", err)
 			http.Error(w, msg, http.StatusInternalServerError)
@@ -156,6 +282,7 @@ func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
 		if _, err := w.Write(errResp); err != nil {
 			msg := fmt.Sprintf("could not write response: %v", err)
@@ -175,9 +302,34 @@ func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	logger.WithValues(log.Kv{
 		"duration": time.Since(t0),
-	}).Infof("Admission review request handled")
+	}).Debugf("Admission review request handled")
 }
+
+// review executes the webhook review, if enabled, recovering the panics of the webhook
+// and returning them as errors.
+func (h handler) review(ctx context.Context, logger log.Logger, ar model.AdmissionReview) (_ model.AdmissionResponse, err error) {
+	if h.recoverPanics {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Errorf("webhook review panicked: %v\n%s", r, debug.Stack())
+				err = fmt.Errorf("webhook review panicked: %v", r)
+			}
+		}()
+	}
+
+	return h.webhook.Review(ctx, ar)
+}
+
 func (h handler) requestBodyToModelReview(body []byte) (*model.AdmissionReview, error) {
+	// Check we have an admission review before decoding, so we return a clear error on other objects.
+	tm := metav1.TypeMeta{}
+	if err := json.Unmarshal(body, &tm); err == nil {
+		if tm.Kind != v1AdmissionReviewTypeMeta.Kind || (tm.APIVersion != v1AdmissionReviewTypeMeta.APIVersion && tm.APIVersion != v1beta1AdmissionReviewTypeMeta.APIVersion) {
+			return nil, fmt.Errorf("invalid admission review, expected %q kind with %q or %q version, got %q kind with %q version",
+				v1AdmissionReviewTypeMeta.Kind, v1AdmissionReviewTypeMeta.APIVersion, v1beta1AdmissionReviewTypeMeta.APIVersion, tm.Kind, tm.APIVersion)
+		}
+	}
+
 	kubeReview, _, err := deserializer.Decode(body, nil, nil)
 	if err != nil {
 		return nil, fmt.Errorf("could not decode the admission review from the request: %w", err)
@@ -185,9 +337,15 @@ func (h handler) requestBodyToModelReview(body []byte) (*model.AdmissionReview,
 
 	switch ar := kubeReview.(type) {
 	case *admissionv1beta1.AdmissionReview:
+		if ar.Request == nil {
+			return &model.AdmissionReview{OriginalAdmissionReview: ar, Version: model.AdmissionReviewVersionV1beta1}, errMissingRequest
+		}
 		res := model.NewAdmissionReviewV1Beta1(ar)
 		return &res, nil
 	case *admissionv1.AdmissionReview:
+		if ar.Request == nil {
+			return &model.AdmissionReview{OriginalAdmissionReview: ar, Version: model.AdmissionReviewVersionV1}, errMissingRequest
+		}
 		res := model.NewAdmissionReviewV1(ar)
 		return &res, nil
 	}
@@ -195,6 +353,89 @@ func (h handler) requestBodyToModelReview(body []byte) (*model.AdmissionReview,
 	return nil, fmt.Errorf("invalid admission review type")
 }
 
+// statusResponseWriter is a response writer that records the response status code.
+type statusResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (s *statusResponseWriter) WriteHeader(code int) {
+	s.statusCode = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// httpTraceError returns the error used to end the HTTP trace, the review errors (including the ones
+// responded as admission review errors with a 200 HTTP code) and the error HTTP code responses.
+func httpTraceError(statusCode int, reviewErr error) error {
+	if reviewErr != nil {
+		return reviewErr
+	}
+
+	if statusCode >= http.StatusBadRequest {
+		return fmt.Errorf("%d %s response", statusCode, http.StatusText(statusCode))
+	}
+
+	return nil
+}
+
+// isRequestBodyTooLargeError checks if the error is the one returned by `http.MaxBytesReader` when the
+// body exceeds the limit, the error type is not exported so we check the error message.
+func isRequestBodyTooLargeError(err error) bool {
+	return strings.Contains(err.Error(), "http: request body too large")
+}
+
+// partialRequestBodyToModelReview makes a best effort to get the admission review version and the
+// request UID from an invalid admission review, so we can respond with a valid admission review error.
+func partialRequestBodyToModelReview(body []byte) (*model.AdmissionReview, bool) {
+	partialReview := struct {
+		metav1.TypeMeta `json:",inline"`
+		Request         json.RawMessage `json:"request"`
+	}{}
+	if err := json.Unmarshal(body, &partialReview); err != nil {
+		return nil, false
+	}
+
+	ar := &model.AdmissionReview{}
+	switch partialReview.GroupVersionKind() {
+	case admissionv1beta1.SchemeGroupVersion.WithKind("AdmissionReview"):
+		ar.OriginalAdmissionReview = &admissionv1beta1.AdmissionReview{}
+		ar.Version = model.AdmissionReviewVersionV1beta1
+	case admissionv1.SchemeGroupVersion.WithKind("AdmissionReview"):
+		ar.OriginalAdmissionReview = &admissionv1.AdmissionReview{}
+		ar.Version = model.AdmissionReviewVersionV1
+	default:
+		return nil, false
+	}
+
+	// The request could be invalid, ignore the errors and use the UID if we got it.
+	partialRequest := struct {
+		UID string `json:"uid"`
+	}{}
+	_ = json.Unmarshal(partialReview.Request, &partialRequest)
+	ar.ID = partialRequest.UID
+
+	return ar, true
+}
+
+var errMissingRequest = errors.New("admission review request is missing")
+
+func (h handler) writeBadRequestResponse(w http.ResponseWriter, review model.AdmissionReview, msg string) {
+	errResp, err := h.errorToJSON(review, webhook.NewStatusError(http.StatusBadRequest, metav1.StatusReasonBadRequest, msg))
+	if err != nil {
+		msg := fmt.Sprintf("could not marshall status error on admission response: %v", err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		h.logger.Errorf(msg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(errResp); err != nil {
+		msg := fmt.Sprintf("could not write response: %v", err)
+		http.Error(w, msg, http.StatusInternalServerError)
+		h.logger.Errorf(msg)
+	}
+}
+
 func (h handler) modelResponseToJSON(ctx context.Context, review model.AdmissionReview, resp model.AdmissionResponse) (data []byte, err error) {
 	switch r := resp.(type) {
 	case *model.ValidatingAdmissionResponse:
@@ -210,10 +451,34 @@ func (h handler) validatingModelResponseToJSON(ctx context.Context, review model
 	// Set the satus code and result based on the validation result.
 	var resultStatus *metav1.Status
 	if !resp.Allowed {
+		code := resp.StatusCode
+		if code == 0 {
+			code = http.StatusBadRequest
+		}
+
 		resultStatus = &metav1.Status{
 			Message: resp.Message,
 			Status:  metav1.StatusFailure,
-			Code:    http.StatusBadRequest,
+			Code:    code,
+		}
+
+		// Return the violations as the status causes.
+		if len(resp.Violations) > 0 {
+			causes := make([]metav1.StatusCause, 0, len(resp.Violations))
+			msgs := make([]string, 0, len(resp.Violations))
+			for _, v := range resp.Violations {
+				causes = append(causes, metav1.StatusCause{
+					Type:    metav1.CauseTypeFieldValueInvalid,
+					Field:   v.Field,
+					Message: v.Message,
+				})
+				msgs = append(msgs, fmt.Sprintf("%s: %s", v.Field, v.Message))
+			}
+			resultStatus.Details = &metav1.StatusDetails{Causes: causes}
+
+			if resultStatus.Message == "" {
+				resultStatus.Message = strings.Join(msgs, ", ")
+			}
 		}
 	}
 
@@ -223,24 +488,26 @@ func (h handler) validatingModelResponseToJSON(ctx context.Context, review model
 			h.logger.WithCtxValues(ctx).Warningf("warnings used in a 'v1beta1' webhook")
 		}
 
-		data, err := json.Marshal(admissionv1beta1.AdmissionReview{
+		data, err := h.marshalResponse(admissionv1beta1.AdmissionReview{
 			TypeMeta: v1beta1AdmissionReviewTypeMeta,
 			Response: &admissionv1beta1.AdmissionResponse{
-				UID:     types.UID(review.ID),
-				Allowed: resp.Allowed,
-				Result:  resultStatus,
+				UID:              types.UID(review.ID),
+				Allowed:          resp.Allowed,
+				Result:           resultStatus,
+				AuditAnnotations: resp.AuditAnnotations,
 			},
 		})
 		return data, err
 
 	case *admissionv1.AdmissionReview:
-		data, err := json.Marshal(admissionv1.AdmissionReview{
+		data, err := h.marshalResponse(admissionv1.AdmissionReview{
 			TypeMeta: v1AdmissionReviewTypeMeta,
 			Response: &admissionv1.AdmissionResponse{
-				UID:      types.UID(review.ID),
-				Warnings: resp.Warnings,
-				Allowed:  resp.Allowed,
-				Result:   resultStatus,
+				UID:              types.UID(review.ID),
+				Warnings:         resp.Warnings,
+				Allowed:          resp.Allowed,
+				Result:           resultStatus,
+				AuditAnnotations: resp.AuditAnnotations,
 			},
 		})
 		return data, err
@@ -256,27 +523,37 @@ func (h handler) mutatingModelResponseToJSON(ctx context.Context, review model.A
 			h.logger.WithCtxValues(ctx).Warningf("warnings used in a 'v1beta1' webhook")
 		}
 
-		data, err := json.Marshal(admissionv1beta1.AdmissionReview{
+		r := &admissionv1beta1.AdmissionResponse{
+			UID:              types.UID(review.ID),
+			Allowed:          true,
+			AuditAnnotations: resp.AuditAnnotations,
+		}
+		if !isEmptyPatch(resp.JSONPatchPatch) {
+			r.PatchType = v1beta1JSONPatchType
+			r.Patch = resp.JSONPatchPatch
+		}
+
+		data, err := h.marshalResponse(admissionv1beta1.AdmissionReview{
 			TypeMeta: v1beta1AdmissionReviewTypeMeta,
-			Response: &admissionv1beta1.AdmissionResponse{
-				UID:       types.UID(review.ID),
-				PatchType: v1beta1JSONPatchType,
-				Patch:     resp.JSONPatchPatch,
-				Allowed:   true,
-			},
+			Response: r,
 		})
 		return data, err
 
 	case *admissionv1.AdmissionReview:
-		data, err := json.Marshal(admissionv1.AdmissionReview{
+		r := &admissionv1.AdmissionResponse{
+			UID:              types.UID(review.ID),
+			Allowed:          true,
+			Warnings:         resp.Warnings,
+			AuditAnnotations: resp.AuditAnnotations,
+		}
+		if !isEmptyPatch(resp.JSONPatchPatch) {
+			r.PatchType = v1JSONPatchType
+			r.Patch = resp.JSONPatchPatch
+		}
+
+		data, err := h.marshalResponse(admissionv1.AdmissionReview{
 			TypeMeta: v1AdmissionReviewTypeMeta,
-			Response: &admissionv1.AdmissionResponse{
-				UID:       types.UID(review.ID),
-				PatchType: v1JSONPatchType,
-				Patch:     resp.JSONPatchPatch,
-				Allowed:   true,
-				Warnings:  resp.Warnings,
-			},
+			Response: r,
 		})
 
 		return data, err
@@ -286,30 +563,26 @@ func (h handler) mutatingModelResponseToJSON(ctx context.Context, review model.A
 }
 
 func (h handler) errorToJSON(review model.AdmissionReview, err error) ([]byte, error) {
+	status := errorToStatus(err)
+
 	switch review.OriginalAdmissionReview.(type) {
 	case *admissionv1beta1.AdmissionReview:
 		r := &admissionv1beta1.AdmissionResponse{
-			UID: types.UID(review.ID),
-			Result: &metav1.Status{
-				Message: err.Error(),
-				Status:  metav1.StatusFailure,
-			},
+			UID:    types.UID(review.ID),
+			Result: status,
 		}
 
-		return json.Marshal(admissionv1beta1.AdmissionReview{
+		return h.marshalResponse(admissionv1beta1.AdmissionReview{
 			TypeMeta: v1beta1AdmissionReviewTypeMeta,
 			Response: r,
 		})
 	case *admissionv1.AdmissionReview:
 		r := &admissionv1.AdmissionResponse{
-			UID: types.UID(review.ID),
-			Result: &metav1.Status{
-				Message: err.Error(),
-				Status:  metav1.StatusFailure,
-			},
+			UID:    types.UID(review.ID),
+			Result: status,
 		}
 
-		return json.Marshal(admissionv1.AdmissionReview{
+		return h.marshalResponse(admissionv1.AdmissionReview{
 			TypeMeta: v1AdmissionReviewTypeMeta,
 			Response: r,
 		})
@@ -318,6 +591,34 @@ func (h handler) errorToJSON(review model.AdmissionReview, err error) ([]byte, e
 	return nil, fmt.Errorf("invalid admission response type")
 }
 
+// errorToStatus converts an error into a Kubernetes status, by default all the errors
+// will be internal errors unless the error is a webhook.StatusError.
+func errorToStatus(err error) *metav1.Status {
+	var statusErr *webhook.StatusError
+	if errors.As(err, &statusErr) {
+		return &metav1.Status{
+			Message: statusErr.Message,
+			Status:  metav1.StatusFailure,
+			Code:    statusErr.Code,
+			Reason:  statusErr.Reason,
+		}
+	}
+
+	return &metav1.Status{
+		Message: err.Error(),
+		Status:  metav1.StatusFailure,
+		Code:    http.StatusInternalServerError,
+		Reason:  metav1.StatusReasonInternalError,
+	}
+}
+
+// isEmptyPatch checks if the JSON patch doesn't have any operation, empty patches
+// are not returned to the apiserver.
+func isEmptyPatch(patch []byte) bool {
+	p := bytes.TrimSpace(patch)
+	return len(p) == 0 || string(p) == "[]"
+}
+
 var (
 	v1beta1JSONPatchType = func() *admissionv1beta1.PatchType {
 		pt := admissionv1beta1.PatchTypeJSONPatch
@@ -338,3 +639,13 @@ var (
 		APIVersion: "admission.k8s.io/v1",
 	}
 )
+
+// marshalResponse marshals the admission review response into JSON, indented if
+// required by the handler configuration.
+func (h handler) marshalResponse(v interface{}) ([]byte, error) {
+	if h.indentResponses {
+		return json.MarshalIndent(v, "", "  ")
+	}
+
+	return json.Marshal(v)
+}
@@ -2,9 +2,11 @@ package http_test
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -20,6 +22,9 @@ import (
 
 	kubewebhookhttp "github.com/slok/kubewebhook/v2/pkg/http"
 	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/tracing"
+	"github.com/slok/kubewebhook/v2/pkg/webhook"
+	"github.com/slok/kubewebhook/v2/pkg/webhook/validating"
 	"github.com/slok/kubewebhook/v2/pkg/webhook/webhookmock"
 )
 
@@ -98,11 +103,89 @@ func TestDefaultWebhookFlow(t *testing.T) {
 	tests := map[string]struct {
 		name           string
 		body           string
+		method         string
+		maxBodyBytes   int64
+		timeout        time.Duration
+		contentType    string
 		mock           func(mw *webhookmock.Webhook)
 		reviewResponse *model.AdmissionResponse
 		expCode        int
 		expBody        string
 	}{
+		"A request with a method different from POST should return error": {
+			method:  "GET",
+			body:    getTestAdmissionReviewV1RequestStr("1234567890"),
+			mock:    func(mw *webhookmock.Webhook) {},
+			expBody: "method not allowed\n",
+			expCode: 405,
+		},
+
+		"A request with a body bigger than the max allowed should return error": {
+			maxBodyBytes: int64(len(getTestAdmissionReviewV1RequestStr("1234567890")) - 1),
+			body:         getTestAdmissionReviewV1RequestStr("1234567890"),
+			mock:         func(mw *webhookmock.Webhook) {},
+			expBody:      "request body too large\n",
+			expCode:      413,
+		},
+
+		"A request with a body of the max allowed size should not fail.": {
+			maxBodyBytes: int64(len(getTestAdmissionReviewV1RequestStr("1234567890"))),
+			body:         getTestAdmissionReviewV1RequestStr("1234567890"),
+			mock: func(mw *webhookmock.Webhook) {
+				resp := &model.ValidatingAdmissionResponse{ID: "1234567890", Allowed: true}
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(resp, nil)
+			},
+			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1","response":{"uid":"1234567890","allowed":true}}`,
+			expCode: 200,
+		},
+
+		"A request with a content type different from JSON should return error": {
+			contentType: "application/yaml",
+			body:        getTestAdmissionReviewV1RequestStr("1234567890"),
+			mock:        func(mw *webhookmock.Webhook) {},
+			expBody:     "unsupported content type\n",
+			expCode:     415,
+		},
+
+		"A request with a JSON content type should not fail.": {
+			contentType: "application/json; charset=utf-8",
+			body:        getTestAdmissionReviewV1RequestStr("1234567890"),
+			mock: func(mw *webhookmock.Webhook) {
+				resp := &model.ValidatingAdmissionResponse{ID: "1234567890", Allowed: true}
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(resp, nil)
+			},
+			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1","response":{"uid":"1234567890","allowed":true}}`,
+			expCode: 200,
+		},
+
+		"An admission review v1beta1 without request should return an admission review error.": {
+			body:    `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1beta1","request":null}`,
+			mock:    func(mw *webhookmock.Webhook) {},
+			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1beta1","response":{"uid":"","allowed":false,"status":{"metadata":{},"status":"Failure","message":"admission review request is missing","reason":"BadRequest","code":400}}}`,
+			expCode: 200,
+		},
+
+		"An admission review v1 without request should return an admission review error.": {
+			body:    `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1"}`,
+			mock:    func(mw *webhookmock.Webhook) {},
+			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1","response":{"uid":"","allowed":false,"status":{"metadata":{},"status":"Failure","message":"admission review request is missing","reason":"BadRequest","code":400}}}`,
+			expCode: 200,
+		},
+
+		"An invalid admission review v1 should return an admission review error with the request UID.": {
+			body:    `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1","request":{"uid":"1234567890","operation":42}}`,
+			mock:    func(mw *webhookmock.Webhook) {},
+			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1","response":{"uid":"1234567890","allowed":false,"status":{"metadata":{},"status":"Failure","message":"could not decode the admission review from the request","reason":"BadRequest","code":400}}}`,
+			expCode: 200,
+		},
+
+		"An invalid admission review v1beta1 with an invalid UID should return an admission review error without the request UID.": {
+			body:    `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1beta1","request":{"uid":42}}`,
+			mock:    func(mw *webhookmock.Webhook) {},
+			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1beta1","response":{"uid":"","allowed":false,"status":{"metadata":{},"status":"Failure","message":"could not decode the admission review from the request","reason":"BadRequest","code":400}}}`,
+			expCode: 200,
+		},
+
 		"No admission review on request should return error": {
 			body:    "",
 			mock:    func(mw *webhookmock.Webhook) {},
@@ -110,6 +193,27 @@ func TestDefaultWebhookFlow(t *testing.T) {
 			expCode: 400,
 		},
 
+		"An empty JSON object on request should return error": {
+			body:    `{}`,
+			mock:    func(mw *webhookmock.Webhook) {},
+			expBody: `invalid admission review, expected "AdmissionReview" kind with "admission.k8s.io/v1" or "admission.k8s.io/v1beta1" version, got "" kind with "" version` + newLine,
+			expCode: 400,
+		},
+
+		"A JSON object that is not an admission review on request should return error": {
+			body:    `{"kind":"Pod","apiVersion":"v1","metadata":{"name":"test"}}`,
+			mock:    func(mw *webhookmock.Webhook) {},
+			expBody: `invalid admission review, expected "AdmissionReview" kind with "admission.k8s.io/v1" or "admission.k8s.io/v1beta1" version, got "Pod" kind with "v1" version` + newLine,
+			expCode: 400,
+		},
+
+		"An admission review with an unknown version on request should return error": {
+			body:    `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v2","request":{"uid":"1234567890"}}`,
+			mock:    func(mw *webhookmock.Webhook) {},
+			expBody: `invalid admission review, expected "AdmissionReview" kind with "admission.k8s.io/v1" or "admission.k8s.io/v1beta1" version, got "AdmissionReview" kind with "admission.k8s.io/v2" version` + newLine,
+			expCode: 400,
+		},
+
 		"Bad admission review on request should return error": {
 			body:    "wrong body",
 			mock:    func(mw *webhookmock.Webhook) {},
@@ -146,6 +250,81 @@ func TestDefaultWebhookFlow(t *testing.T) {
 			expCode: 200,
 		},
 
+		"A correct validation admission v1 webhook that doesn't allow with a custom status code should not fail.": {
+			body: getTestAdmissionReviewV1RequestStr("1234567890"),
+			mock: func(mw *webhookmock.Webhook) {
+				resp := &model.ValidatingAdmissionResponse{
+					ID:         "1234567890",
+					Allowed:    false,
+					Message:    "privileged pods are forbidden",
+					StatusCode: 403,
+				}
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(resp, nil)
+			},
+			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1","response":{"uid":"1234567890","allowed":false,"status":{"metadata":{},"status":"Failure","message":"privileged pods are forbidden","code":403}}}`,
+			expCode: 200,
+		},
+
+		"A correct validation admission v1 webhook with violations should return the violations as the status causes.": {
+			body: getTestAdmissionReviewV1RequestStr("1234567890"),
+			mock: func(mw *webhookmock.Webhook) {
+				resp := &model.ValidatingAdmissionResponse{
+					ID:      "1234567890",
+					Allowed: false,
+					Violations: []model.Violation{
+						{Field: "spec.containers[0].image", Message: "latest tag is forbidden"},
+						{Field: "spec.containers[0].securityContext.privileged", Message: "privileged containers are forbidden"},
+						{Field: "spec.hostNetwork", Message: "host network is forbidden"},
+					},
+				}
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(resp, nil)
+			},
+			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1","response":{"uid":"1234567890","allowed":false,"status":{"metadata":{},"status":"Failure","message":"spec.containers[0].image: latest tag is forbidden, spec.containers[0].securityContext.privileged: privileged containers are forbidden, spec.hostNetwork: host network is forbidden","details":{"causes":[{"reason":"FieldValueInvalid","message":"latest tag is forbidden","field":"spec.containers[0].image"},{"reason":"FieldValueInvalid","message":"privileged containers are forbidden","field":"spec.containers[0].securityContext.privileged"},{"reason":"FieldValueInvalid","message":"host network is forbidden","field":"spec.hostNetwork"}]},"code":400}}}`,
+			expCode: 200,
+		},
+
+		"A correct validation admission v1beta1 webhook with violations and message should return the message and the violations as the status causes.": {
+			body: getTestAdmissionReviewV1beta1RequestStr("1234567890"),
+			mock: func(mw *webhookmock.Webhook) {
+				resp := &model.ValidatingAdmissionResponse{
+					ID:         "1234567890",
+					Allowed:    false,
+					Message:    "invalid pod",
+					Violations: []model.Violation{{Field: "spec.hostNetwork", Message: "host network is forbidden"}},
+				}
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(resp, nil)
+			},
+			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1beta1","response":{"uid":"1234567890","allowed":false,"status":{"metadata":{},"status":"Failure","message":"invalid pod","details":{"causes":[{"reason":"FieldValueInvalid","message":"host network is forbidden","field":"spec.hostNetwork"}]},"code":400}}}`,
+			expCode: 200,
+		},
+
+		"A correct validation admission v1 webhook with audit annotations should not fail.": {
+			body: getTestAdmissionReviewV1RequestStr("1234567890"),
+			mock: func(mw *webhookmock.Webhook) {
+				resp := &model.ValidatingAdmissionResponse{
+					ID:               "1234567890",
+					Allowed:          true,
+					AuditAnnotations: map[string]string{"reason": "trusted"},
+				}
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(resp, nil)
+			},
+			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1","response":{"uid":"1234567890","allowed":true,"auditAnnotations":{"reason":"trusted"}}}`,
+			expCode: 200,
+		},
+
+		"A correct mutating admission v1beta1 webhook with audit annotations should not fail.": {
+			body: getTestAdmissionReviewV1beta1RequestStr("1234567890"),
+			mock: func(mw *webhookmock.Webhook) {
+				resp := &model.MutatingAdmissionResponse{
+					ID:               "1234567890",
+					AuditAnnotations: map[string]string{"reason": "defaulted"},
+				}
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(resp, nil)
+			},
+			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1beta1","response":{"uid":"1234567890","allowed":true,"auditAnnotations":{"reason":"defaulted"}}}`,
+			expCode: 200,
+		},
+
 		"A correct mutating admission v1beta1 webhook with mutation should not fail.": {
 			body: getTestAdmissionReviewV1beta1RequestStr("1234567890"),
 			mock: func(mw *webhookmock.Webhook) {
@@ -170,7 +349,116 @@ func TestDefaultWebhookFlow(t *testing.T) {
 				}
 				mw.On("Review", mock.Anything, mock.Anything).Once().Return(resp, nil)
 			},
-			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1beta1","response":{"uid":"1234567890","allowed":true,"patchType":"JSONPatch"}}`,
+			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1beta1","response":{"uid":"1234567890","allowed":true}}`,
+			expCode: 200,
+		},
+
+		"A correct validation admission v1beta1 webhook from an old apiserver (without request kind) should not fail.": {
+			body: func() string {
+				ar := &admissionv1beta1.AdmissionReview{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "AdmissionReview",
+						APIVersion: "admission.k8s.io/v1beta1",
+					},
+					Request: &admissionv1beta1.AdmissionRequest{
+						Kind: metav1.GroupVersionKind{
+							Group:   "core",
+							Kind:    "Pod",
+							Version: "v1",
+						},
+						UID: types.UID("1234567890"),
+					},
+				}
+				var b bytes.Buffer
+				_ = encoder.Encode(ar, &b)
+				return b.String()
+			}(),
+			mock: func(mw *webhookmock.Webhook) {
+				exp := mock.MatchedBy(func(ar model.AdmissionReview) bool {
+					return ar.RequestGVK != nil && ar.RequestGVK.Kind == "Pod"
+				})
+				resp := &model.ValidatingAdmissionResponse{
+					ID:      "1234567890",
+					Allowed: true,
+				}
+				mw.On("Review", mock.Anything, exp).Once().Return(resp, nil)
+			},
+			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1beta1","response":{"uid":"1234567890","allowed":true}}`,
+			expCode: 200,
+		},
+
+		"A v1beta1 dry-run admission review should pass the dry-run flag to the webhook.": {
+			body: func() string {
+				dryRun := true
+				ar := &admissionv1beta1.AdmissionReview{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "AdmissionReview",
+						APIVersion: "admission.k8s.io/v1beta1",
+					},
+					Request: &admissionv1beta1.AdmissionRequest{
+						Kind:   metav1.GroupVersionKind{Group: "core", Kind: "Pod", Version: "v1"},
+						UID:    types.UID("1234567890"),
+						DryRun: &dryRun,
+					},
+				}
+				var b bytes.Buffer
+				_ = encoder.Encode(ar, &b)
+				return b.String()
+			}(),
+			mock: func(mw *webhookmock.Webhook) {
+				exp := mock.MatchedBy(func(ar model.AdmissionReview) bool { return ar.DryRun })
+				resp := &model.ValidatingAdmissionResponse{
+					ID:      "1234567890",
+					Allowed: true,
+				}
+				mw.On("Review", mock.Anything, exp).Once().Return(resp, nil)
+			},
+			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1beta1","response":{"uid":"1234567890","allowed":true}}`,
+			expCode: 200,
+		},
+
+		"A v1 dry-run admission review should pass the dry-run flag to the webhook.": {
+			body: func() string {
+				dryRun := true
+				ar := &admissionv1.AdmissionReview{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "AdmissionReview",
+						APIVersion: "admission.k8s.io/v1",
+					},
+					Request: &admissionv1.AdmissionRequest{
+						Kind:   metav1.GroupVersionKind{Group: "core", Kind: "Pod", Version: "v1"},
+						UID:    types.UID("1234567890"),
+						DryRun: &dryRun,
+					},
+				}
+				var b bytes.Buffer
+				_ = encoder.Encode(ar, &b)
+				return b.String()
+			}(),
+			mock: func(mw *webhookmock.Webhook) {
+				exp := mock.MatchedBy(func(ar model.AdmissionReview) bool { return ar.DryRun })
+				resp := &model.ValidatingAdmissionResponse{
+					ID:      "1234567890",
+					Allowed: true,
+				}
+				mw.On("Review", mock.Anything, exp).Once().Return(resp, nil)
+			},
+			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1","response":{"uid":"1234567890","allowed":true}}`,
+			expCode: 200,
+		},
+
+		"A handler with timeout should review with a context with deadline.": {
+			timeout: 5 * time.Second,
+			body:    getTestAdmissionReviewV1RequestStr("1234567890"),
+			mock: func(mw *webhookmock.Webhook) {
+				expCtx := mock.MatchedBy(func(ctx context.Context) bool {
+					_, ok := ctx.Deadline()
+					return ok
+				})
+				resp := &model.ValidatingAdmissionResponse{ID: "1234567890", Allowed: true}
+				mw.On("Review", expCtx, mock.Anything).Once().Return(resp, nil)
+			},
+			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1","response":{"uid":"1234567890","allowed":true}}`,
 			expCode: 200,
 		},
 
@@ -227,7 +515,20 @@ func TestDefaultWebhookFlow(t *testing.T) {
 				}
 				mw.On("Review", mock.Anything, mock.Anything).Once().Return(resp, nil)
 			},
-			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1","response":{"uid":"1234567890","allowed":true,"patchType":"JSONPatch","warnings":["warn1","warn2"]}}`,
+			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1","response":{"uid":"1234567890","allowed":true,"warnings":["warn1","warn2"]}}`,
+			expCode: 200,
+		},
+
+		"A correct mutating admission v1 webhook with an empty patch should not return the patch.": {
+			body: getTestAdmissionReviewV1RequestStr("1234567890"),
+			mock: func(mw *webhookmock.Webhook) {
+				resp := &model.MutatingAdmissionResponse{
+					ID:             "1234567890",
+					JSONPatchPatch: []byte(`[]`),
+				}
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(resp, nil)
+			},
+			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1","response":{"uid":"1234567890","allowed":true}}`,
 			expCode: 200,
 		},
 
@@ -236,7 +537,7 @@ func TestDefaultWebhookFlow(t *testing.T) {
 			mock: func(mw *webhookmock.Webhook) {
 				mw.On("Review", mock.Anything, mock.Anything).Once().Return(nil, fmt.Errorf("wanted error"))
 			},
-			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1beta1","response":{"uid":"1234567890","allowed":false,"status":{"metadata":{},"status":"Failure","message":"wanted error"}}}`,
+			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1beta1","response":{"uid":"1234567890","allowed":false,"status":{"metadata":{},"status":"Failure","message":"wanted error","reason":"InternalError","code":500}}}`,
 			expCode: 500,
 		},
 
@@ -245,9 +546,29 @@ func TestDefaultWebhookFlow(t *testing.T) {
 			mock: func(mw *webhookmock.Webhook) {
 				mw.On("Review", mock.Anything, mock.Anything).Once().Return(nil, fmt.Errorf("wanted error"))
 			},
-			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1","response":{"uid":"1234567890","allowed":false,"status":{"metadata":{},"status":"Failure","message":"wanted error"}}}`,
+			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1","response":{"uid":"1234567890","allowed":false,"status":{"metadata":{},"status":"Failure","message":"wanted error","reason":"InternalError","code":500}}}`,
 			expCode: 500,
 		},
+
+		"A regular admission v1beta1 call to the webhook handler that returns a status error should return the custom status.": {
+			body: getTestAdmissionReviewV1beta1RequestStr("1234567890"),
+			mock: func(mw *webhookmock.Webhook) {
+				err := fmt.Errorf("could not mutate: %w", webhook.NewStatusError(403, metav1.StatusReasonForbidden, "not allowed by policy"))
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(nil, err)
+			},
+			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1beta1","response":{"uid":"1234567890","allowed":false,"status":{"metadata":{},"status":"Failure","message":"not allowed by policy","reason":"Forbidden","code":403}}}`,
+			expCode: 200,
+		},
+
+		"A regular admission v1 call to the webhook handler that returns a status error should return the custom status.": {
+			body: getTestAdmissionReviewV1RequestStr("1234567890"),
+			mock: func(mw *webhookmock.Webhook) {
+				err := fmt.Errorf("could not mutate: %w", webhook.NewStatusError(403, metav1.StatusReasonForbidden, "not allowed by policy"))
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(nil, err)
+			},
+			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1","response":{"uid":"1234567890","allowed":false,"status":{"metadata":{},"status":"Failure","message":"not allowed by policy","reason":"Forbidden","code":403}}}`,
+			expCode: 200,
+		},
 	}
 
 	for name, test := range tests {
@@ -261,10 +582,17 @@ func TestDefaultWebhookFlow(t *testing.T) {
 			mwh.On("ID").Maybe().Return("")
 			mwh.On("Kind").Maybe().Return(model.WebhookKind(""))
 
-			h, err := kubewebhookhttp.HandlerFor(kubewebhookhttp.HandlerConfig{Webhook: mwh})
+			h, err := kubewebhookhttp.HandlerFor(kubewebhookhttp.HandlerConfig{Webhook: mwh, MaxRequestBodyBytes: test.maxBodyBytes, Timeout: test.timeout})
 			require.NoError(err)
 
-			req := httptest.NewRequest("GET", "/awesome/webhook", bytes.NewBufferString(test.body))
+			method := test.method
+			if method == "" {
+				method = "POST"
+			}
+			req := httptest.NewRequest(method, "/awesome/webhook", bytes.NewBufferString(test.body))
+			if test.contentType != "" {
+				req.Header.Set("Content-Type", test.contentType)
+			}
 			w := httptest.NewRecorder()
 			h.ServeHTTP(w, req)
 
@@ -273,3 +601,300 @@ func TestDefaultWebhookFlow(t *testing.T) {
 		})
 	}
 }
+
+func TestHandlerPanicRecovery(t *testing.T) {
+	tests := map[string]struct {
+		disablePanicRecovery bool
+		expPanic             bool
+		expCode              int
+		expBody              string
+	}{
+		"A panic on the webhook review should be recovered and return an error.": {
+			expCode: 500,
+			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1","response":{"uid":"1234567890","allowed":false,"status":{"metadata":{},"status":"Failure","message":"webhook review panicked: assignment to entry in nil map","reason":"InternalError","code":500}}}`,
+		},
+
+		"A panic on the webhook review with the panic recovery disabled should panic.": {
+			disablePanicRecovery: true,
+			expPanic:             true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			// Mocks.
+			mwh := &webhookmock.Webhook{}
+			mwh.On("ID").Maybe().Return("")
+			mwh.On("Kind").Maybe().Return(model.WebhookKind(""))
+			mwh.On("Review", mock.Anything, mock.Anything).Once().Run(func(_ mock.Arguments) {
+				var m map[string]string
+				m["panic"] = "true"
+			})
+
+			h, err := kubewebhookhttp.HandlerFor(kubewebhookhttp.HandlerConfig{Webhook: mwh, DisablePanicRecovery: test.disablePanicRecovery})
+			require.NoError(err)
+
+			req := httptest.NewRequest("POST", "/awesome/webhook", bytes.NewBufferString(getTestAdmissionReviewV1RequestStr("1234567890")))
+			w := httptest.NewRecorder()
+
+			if test.expPanic {
+				assert.Panics(func() { h.ServeHTTP(w, req) })
+				return
+			}
+
+			h.ServeHTTP(w, req)
+			assert.Equal(test.expCode, w.Code)
+			assert.Equal(test.expBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandlerIndentResponses(t *testing.T) {
+	tests := map[string]struct {
+		indentResponses bool
+		expBody         string
+	}{
+		"By default the responses should be compact.": {
+			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1","response":{"uid":"1234567890","allowed":true,"auditAnnotations":{"reason":"defaulted"}}}`,
+		},
+
+		"Having indented responses enabled, the responses should be indented.": {
+			indentResponses: true,
+			expBody: `{
+  "kind": "AdmissionReview",
+  "apiVersion": "admission.k8s.io/v1",
+  "response": {
+    "uid": "1234567890",
+    "allowed": true,
+    "auditAnnotations": {
+      "reason": "defaulted"
+    }
+  }
+}`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			// Mocks.
+			mwh := &webhookmock.Webhook{}
+			mwh.On("ID").Maybe().Return("")
+			mwh.On("Kind").Maybe().Return(model.WebhookKind(model.WebhookKindMutating))
+			resp := &model.MutatingAdmissionResponse{
+				ID:               "1234567890",
+				AuditAnnotations: map[string]string{"reason": "defaulted"},
+			}
+			mwh.On("Review", mock.Anything, mock.Anything).Return(resp, nil)
+
+			h, err := kubewebhookhttp.HandlerFor(kubewebhookhttp.HandlerConfig{Webhook: mwh, IndentResponses: test.indentResponses})
+			require.NoError(err)
+
+			// Serve the same request multiple times, the responses should be the same.
+			for i := 0; i < 3; i++ {
+				req := httptest.NewRequest("POST", "/awesome/webhook", bytes.NewBufferString(getTestAdmissionReviewV1RequestStr("1234567890")))
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, req)
+
+				assert.Equal(200, w.Code)
+				assert.Equal(test.expBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandlerDeleteReview(t *testing.T) {
+	// On delete operations the apiserver sends the deleted object as the old object.
+	deleteReview := func(version string) string {
+		return `{
+  "kind": "AdmissionReview",
+  "apiVersion": "admission.k8s.io/` + version + `",
+  "request": {
+    "uid": "1234567890",
+    "kind": {"group": "", "version": "v1", "kind": "Pod"},
+    "resource": {"group": "", "version": "v1", "resource": "pods"},
+    "name": "test",
+    "namespace": "test-ns",
+    "operation": "DELETE",
+    "userInfo": {"username": "test"},
+    "object": null,
+    "oldObject": {"kind": "Pod", "apiVersion": "v1", "metadata": {"name": "test", "namespace": "test-ns", "labels": {"protected": "true"}}},
+    "dryRun": false
+  }
+}`
+	}
+
+	tests := map[string]struct {
+		review  string
+		expBody string
+	}{
+		"A v1 delete review should validate the deleted object.": {
+			review:  deleteReview("v1"),
+			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1","response":{"uid":"1234567890","allowed":false,"status":{"metadata":{},"status":"Failure","message":"test is protected","code":400}}}`,
+		},
+
+		"A v1beta1 delete review should validate the deleted object.": {
+			review:  deleteReview("v1beta1"),
+			expBody: `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1beta1","response":{"uid":"1234567890","allowed":false,"status":{"metadata":{},"status":"Failure","message":"test is protected","code":400}}}`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			val := validating.ValidatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*validating.ValidatorResult, error) {
+				if obj.GetLabels()["protected"] == "true" {
+					return &validating.ValidatorResult{Valid: false, Message: obj.GetName() + " is protected"}, nil
+				}
+				return &validating.ValidatorResult{Valid: true}, nil
+			})
+			wh, err := validating.NewWebhook(validating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}, Validator: val})
+			require.NoError(err)
+
+			h, err := kubewebhookhttp.HandlerFor(kubewebhookhttp.HandlerConfig{Webhook: wh})
+			require.NoError(err)
+
+			req := httptest.NewRequest("POST", "/awesome/webhook", bytes.NewBufferString(test.review))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			assert.Equal(200, w.Code)
+			assert.Equal(test.expBody, w.Body.String())
+		})
+	}
+}
+
+type errReader struct{ err error }
+
+func (e errReader) Read(_ []byte) (int, error) { return 0, e.err }
+
+func TestHandlerBodyReadError(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	mwh := &webhookmock.Webhook{}
+	mwh.On("ID").Maybe().Return("")
+	mwh.On("Kind").Maybe().Return(model.WebhookKind(""))
+
+	h, err := kubewebhookhttp.HandlerFor(kubewebhookhttp.HandlerConfig{Webhook: mwh})
+	require.NoError(err)
+
+	// A body that fails to be read (e.g connection problems) is not a too large body.
+	req := httptest.NewRequest("POST", "/awesome/webhook", errReader{err: fmt.Errorf("wanted error")})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	assert.Equal(400, w.Code)
+	assert.Equal("could not read request body\n", w.Body.String())
+	mwh.AssertNotCalled(t, "Review", mock.Anything, mock.Anything)
+}
+
+func TestHandlerInvalidWebhookResponse(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// A webhook without response can't be converted to an admission review response.
+	mwh := &webhookmock.Webhook{}
+	mwh.On("ID").Maybe().Return("")
+	mwh.On("Kind").Maybe().Return(model.WebhookKind(model.WebhookKindValidating))
+	mwh.On("Review", mock.Anything, mock.Anything).Once().Return(nil, nil)
+
+	h, err := kubewebhookhttp.HandlerFor(kubewebhookhttp.HandlerConfig{Webhook: mwh})
+	require.NoError(err)
+
+	req := httptest.NewRequest("POST", "/awesome/webhook", bytes.NewBufferString(getTestAdmissionReviewV1RequestStr("1234567890")))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	// The error should be returned as an admission review.
+	assert.Equal(500, w.Code)
+	assert.Equal("application/json", w.Header().Get("Content-Type"))
+	assert.Equal(`{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1","response":{"uid":"1234567890","allowed":false,"status":{"metadata":{},"status":"Failure","message":"unknown webhook response type","reason":"InternalError","code":500}}}`, w.Body.String())
+}
+
+// errRecorderTracer is a tracer that records the errors of the ended traces.
+type errRecorderTracer struct {
+	tracing.Tracer
+	errs *[]error
+}
+
+func (e errRecorderTracer) EndTrace(_ context.Context, err error) { *e.errs = append(*e.errs, err) }
+
+func TestHandlerTracing(t *testing.T) {
+	tests := map[string]struct {
+		method string
+		body   string
+		mock   func(mw *webhookmock.Webhook)
+		expErr string
+	}{
+		"A handled review should end the trace without error.": {
+			body: getTestAdmissionReviewV1RequestStr("1234567890"),
+			mock: func(mw *webhookmock.Webhook) {
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(&model.ValidatingAdmissionResponse{ID: "1234567890", Allowed: true}, nil)
+			},
+		},
+
+		"A failed review should end the trace with the review error.": {
+			body: getTestAdmissionReviewV1RequestStr("1234567890"),
+			mock: func(mw *webhookmock.Webhook) {
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(nil, fmt.Errorf("wanted error"))
+			},
+			expErr: "wanted error",
+		},
+
+		"A review denied with a status error should end the trace with the review error.": {
+			body: getTestAdmissionReviewV1RequestStr("1234567890"),
+			mock: func(mw *webhookmock.Webhook) {
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(nil, webhook.NewStatusError(403, metav1.StatusReasonForbidden, "not allowed by policy"))
+			},
+			expErr: "not allowed by policy",
+		},
+
+		"An error HTTP response should end the trace with an error.": {
+			method: "GET",
+			body:   getTestAdmissionReviewV1RequestStr("1234567890"),
+			mock:   func(mw *webhookmock.Webhook) {},
+			expErr: "405 Method Not Allowed response",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			// Mocks.
+			mwh := &webhookmock.Webhook{}
+			test.mock(mwh)
+			mwh.On("ID").Maybe().Return("")
+			mwh.On("Kind").Maybe().Return(model.WebhookKind(model.WebhookKindValidating))
+
+			errs := []error{}
+			tracer := errRecorderTracer{Tracer: tracing.Noop, errs: &errs}
+			h, err := kubewebhookhttp.HandlerFor(kubewebhookhttp.HandlerConfig{Webhook: mwh, Tracer: tracer})
+			require.NoError(err)
+
+			method := test.method
+			if method == "" {
+				method = "POST"
+			}
+			req := httptest.NewRequest(method, "/awesome/webhook", bytes.NewBufferString(test.body))
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			// Check.
+			require.Len(errs, 1)
+			if test.expErr == "" {
+				assert.NoError(errs[0])
+			} else {
+				assert.EqualError(errs[0], test.expErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// HealthHandler returns a handler that always responds with a 200, it can be used as the
+// liveness probe endpoint (e.g `/healthz`) of the webhook server.
+func HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+}
+
+// ReadinessGate is a handler that can be used as the readiness probe endpoint (e.g `/readyz`)
+// of the webhook server. It will respond with a 503 until it's set as ready (e.g after the TLS
+// certificates have been loaded and the webhooks created), after that it will respond with a 200.
+//
+// It's safe to use concurrently.
+type ReadinessGate struct {
+	ready int32
+}
+
+// NewReadinessGate returns a new not ready ReadinessGate.
+func NewReadinessGate() *ReadinessGate {
+	return &ReadinessGate{}
+}
+
+// SetReady sets the readiness of the gate.
+func (r *ReadinessGate) SetReady(ready bool) {
+	var v int32
+	if ready {
+		v = 1
+	}
+	atomic.StoreInt32(&r.ready, v)
+}
+
+// IsReady returns if the gate is ready.
+func (r *ReadinessGate) IsReady() bool {
+	return atomic.LoadInt32(&r.ready) == 1
+}
+
+// ServeHTTP satisfies http.Handler interface.
+func (r *ReadinessGate) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	if !r.IsReady() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+}
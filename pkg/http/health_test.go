@@ -0,0 +1,70 @@
+package http_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	kubewebhookhttp "github.com/slok/kubewebhook/v2/pkg/http"
+)
+
+func TestHealthHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	req := httptest.NewRequest("GET", "/healthz", nil)
+	w := httptest.NewRecorder()
+	kubewebhookhttp.HealthHandler().ServeHTTP(w, req)
+
+	assert.Equal(200, w.Code)
+	assert.Equal("ok", w.Body.String())
+}
+
+func TestReadinessGate(t *testing.T) {
+	tests := map[string]struct {
+		setReady func(r *kubewebhookhttp.ReadinessGate)
+		expCode  int
+		expBody  string
+	}{
+		"A new readiness gate should not be ready.": {
+			setReady: func(r *kubewebhookhttp.ReadinessGate) {},
+			expCode:  503,
+			expBody:  "not ready\n",
+		},
+
+		"A readiness gate set as ready should be ready.": {
+			setReady: func(r *kubewebhookhttp.ReadinessGate) { r.SetReady(true) },
+			expCode:  200,
+			expBody:  "ok",
+		},
+
+		"A readiness gate set as not ready after being ready should not be ready.": {
+			setReady: func(r *kubewebhookhttp.ReadinessGate) {
+				r.SetReady(true)
+				r.SetReady(false)
+			},
+			expCode: 503,
+			expBody: "not ready\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			r := kubewebhookhttp.NewReadinessGate()
+			test.setReady(r)
+
+			// Use a mux to check it can be served with the rest of the handlers.
+			mux := http.NewServeMux()
+			mux.Handle("/readyz", r)
+			req := httptest.NewRequest("GET", "/readyz", nil)
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+
+			assert.Equal(test.expCode, w.Code)
+			assert.Equal(test.expBody, w.Body.String())
+		})
+	}
+}
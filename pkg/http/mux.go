@@ -0,0 +1,37 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/slok/kubewebhook/v2/pkg/webhook"
+)
+
+// MuxFor returns a new http.Handler that serves multiple webhooks on the same server, each
+// webhook will be served on its path (e.g `/mutate` and `/validate`).
+//
+// Every webhook will have its own handler created with HandlerFor using the same handler
+// configuration, the webhook of the configuration is ignored and will be replaced with the
+// webhook of the path.
+func MuxFor(webhooks map[string]webhook.Webhook, config HandlerConfig) (http.Handler, error) {
+	if len(webhooks) == 0 {
+		return nil, fmt.Errorf("at least one webhook is required")
+	}
+
+	mux := http.NewServeMux()
+	for path, wh := range webhooks {
+		if path == "" {
+			return nil, fmt.Errorf("webhook path can't be empty")
+		}
+
+		cfg := config
+		cfg.Webhook = wh
+		h, err := HandlerFor(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("could not create %q path handler: %w", path, err)
+		}
+		mux.Handle(path, h)
+	}
+
+	return mux, nil
+}
@@ -0,0 +1,97 @@
+package http_test
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	kubewebhookhttp "github.com/slok/kubewebhook/v2/pkg/http"
+	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/webhook"
+	"github.com/slok/kubewebhook/v2/pkg/webhook/webhookmock"
+)
+
+func TestMuxFor(t *testing.T) {
+	newWebhook := func(kind model.WebhookKind, resp model.AdmissionResponse) webhook.Webhook {
+		mwh := &webhookmock.Webhook{}
+		mwh.On("ID").Maybe().Return(string(kind))
+		mwh.On("Kind").Maybe().Return(kind)
+		mwh.On("Review", mock.Anything, mock.Anything).Maybe().Return(resp, nil)
+		return mwh
+	}
+	webhooks := func() map[string]webhook.Webhook {
+		return map[string]webhook.Webhook{
+			"/mutate":   newWebhook(model.WebhookKindMutating, &model.MutatingAdmissionResponse{ID: "1234567890", AuditAnnotations: map[string]string{"wh": "mutate"}}),
+			"/validate": newWebhook(model.WebhookKindValidating, &model.ValidatingAdmissionResponse{ID: "1234567890", Allowed: true, AuditAnnotations: map[string]string{"wh": "validate"}}),
+		}
+	}
+
+	tests := map[string]struct {
+		webhooks map[string]webhook.Webhook
+		path     string
+		expErr   bool
+		expCode  int
+		expBody  string
+	}{
+		"Not having webhooks should fail.": {
+			webhooks: map[string]webhook.Webhook{},
+			expErr:   true,
+		},
+
+		"Having a webhook with an empty path should fail.": {
+			webhooks: map[string]webhook.Webhook{"": newWebhook(model.WebhookKindMutating, nil)},
+			expErr:   true,
+		},
+
+		"Having a nil webhook should fail.": {
+			webhooks: map[string]webhook.Webhook{"/mutate": nil},
+			expErr:   true,
+		},
+
+		"A request on the mutating webhook path should be handled by the mutating webhook.": {
+			webhooks: webhooks(),
+			path:     "/mutate",
+			expCode:  200,
+			expBody:  `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1","response":{"uid":"1234567890","allowed":true,"auditAnnotations":{"wh":"mutate"}}}`,
+		},
+
+		"A request on the validating webhook path should be handled by the validating webhook.": {
+			webhooks: webhooks(),
+			path:     "/validate",
+			expCode:  200,
+			expBody:  `{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1","response":{"uid":"1234567890","allowed":true,"auditAnnotations":{"wh":"validate"}}}`,
+		},
+
+		"A request on an unknown path should not be handled.": {
+			webhooks: webhooks(),
+			path:     "/other",
+			expCode:  404,
+			expBody:  "404 page not found\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			h, err := kubewebhookhttp.MuxFor(test.webhooks, kubewebhookhttp.HandlerConfig{})
+			if test.expErr {
+				assert.Error(err)
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+
+			req := httptest.NewRequest("POST", test.path, bytes.NewBufferString(getTestAdmissionReviewV1RequestStr("1234567890")))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			assert.Equal(test.expCode, w.Code)
+			assert.Equal(test.expBody, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package http
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/slok/kubewebhook/v2/pkg/log"
+)
+
+const defaultCertReloaderCheckInterval = 10 * time.Second
+
+// CertReloaderConfig is the configuration of the CertReloader.
+type CertReloaderConfig struct {
+	// CertFile is the path to the TLS certificate file.
+	CertFile string
+	// KeyFile is the path to the TLS key file.
+	KeyFile string
+	// CheckInterval is the minimum interval between the checks of the certificate files
+	// for changes. By default 10s.
+	CheckInterval time.Duration
+	// Logger is the logger.
+	Logger log.Logger
+}
+
+func (c *CertReloaderConfig) defaults() error {
+	if c.CertFile == "" {
+		return fmt.Errorf("cert file is required")
+	}
+
+	if c.KeyFile == "" {
+		return fmt.Errorf("key file is required")
+	}
+
+	if c.CheckInterval == 0 {
+		c.CheckInterval = defaultCertReloaderCheckInterval
+	}
+
+	if c.CheckInterval < 0 {
+		return fmt.Errorf("check interval can't be negative")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Noop
+	}
+	c.Logger = c.Logger.WithValues(log.Kv{"svc": "http.CertReloader"})
+
+	return nil
+}
+
+// CertReloader serves the TLS certificate from disk, reloading it when the certificate
+// files change (e.g rotated by cert-manager) without the need of restarting the server.
+//
+// The files are checked for changes on the TLS handshakes, at most once every check interval.
+// If the reload of the changed files fails, it will continue serving the previous certificate.
+// The symlinks are followed, so it can be used with the files of Kubernetes secret volumes.
+type CertReloader struct {
+	cfg         CertReloaderConfig
+	mu          sync.Mutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+	lastCheck   time.Time
+}
+
+// NewCertReloader returns a new CertReloader with the certificate already loaded.
+func NewCertReloader(config CertReloaderConfig) (*CertReloader, error) {
+	err := config.defaults()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	c := &CertReloader{cfg: config}
+	certModTime, keyModTime, err := c.modTimes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.load(certModTime, keyModTime)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// GetCertificate satisfies `tls.Config.GetCertificate`.
+func (c *CertReloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.lastCheck) < c.cfg.CheckInterval {
+		return c.cert, nil
+	}
+	c.lastCheck = time.Now()
+
+	certModTime, keyModTime, err := c.modTimes()
+	if err != nil {
+		c.cfg.Logger.Errorf("could not check TLS certificate changes: %s", err)
+		return c.cert, nil
+	}
+
+	if certModTime.Equal(c.certModTime) && keyModTime.Equal(c.keyModTime) {
+		return c.cert, nil
+	}
+
+	err = c.load(certModTime, keyModTime)
+	if err != nil {
+		c.cfg.Logger.Errorf("could not reload TLS certificate: %s", err)
+		return c.cert, nil
+	}
+	c.cfg.Logger.Infof("TLS certificate reloaded")
+
+	return c.cert, nil
+}
+
+// TLSConfig returns a TLS configuration that serves the reloaded certificates.
+func (c *CertReloader) TLSConfig() *tls.Config {
+	return &tls.Config{GetCertificate: c.GetCertificate}
+}
+
+func (c *CertReloader) load(certModTime, keyModTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(c.cfg.CertFile, c.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("could not load TLS certificate: %w", err)
+	}
+
+	c.cert = &cert
+	c.certModTime = certModTime
+	c.keyModTime = keyModTime
+
+	return nil
+}
+
+func (c *CertReloader) modTimes() (certModTime, keyModTime time.Time, err error) {
+	certInfo, err := os.Stat(c.cfg.CertFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("could not stat cert file: %w", err)
+	}
+
+	keyInfo, err := os.Stat(c.cfg.KeyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("could not stat key file: %w", err)
+	}
+
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
@@ -0,0 +1,186 @@
+package http_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	kubewebhookhttp "github.com/slok/kubewebhook/v2/pkg/http"
+)
+
+// newTestCert returns a new self signed PEM encoded certificate and key.
+func newTestCert(t *testing.T, cn string) (cert, key []byte) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &priv.PublicKey, priv)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(priv)
+	require.NoError(t, err)
+
+	cert = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	key = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return cert, key
+}
+
+func TestCertReloader(t *testing.T) {
+	tests := map[string]struct {
+		update func(t *testing.T, certFile, keyFile string)
+		expCN  string
+	}{
+		"Without changes on the files, the certificate should be the loaded one.": {
+			update: func(t *testing.T, certFile, keyFile string) {},
+			expCN:  "cert1",
+		},
+
+		"Having changes on the files, the certificate should be reloaded.": {
+			update: func(t *testing.T, certFile, keyFile string) {
+				cert, key := newTestCert(t, "cert2")
+				require.NoError(t, ioutil.WriteFile(certFile, cert, 0600))
+				require.NoError(t, ioutil.WriteFile(keyFile, key, 0600))
+			},
+			expCN: "cert2",
+		},
+
+		"Having invalid changes on the files, the certificate should be the previous one.": {
+			update: func(t *testing.T, certFile, keyFile string) {
+				require.NoError(t, ioutil.WriteFile(certFile, []byte("wrong"), 0600))
+			},
+			expCN: "cert1",
+		},
+
+		"Having the files removed, the certificate should be the previous one.": {
+			update: func(t *testing.T, certFile, keyFile string) {
+				require.NoError(t, os.Remove(certFile))
+			},
+			expCN: "cert1",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			dir, err := ioutil.TempDir("", "kubewebhook-tls")
+			require.NoError(err)
+			defer os.RemoveAll(dir)
+
+			certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+			cert, key := newTestCert(t, "cert1")
+			require.NoError(ioutil.WriteFile(certFile, cert, 0600))
+			require.NoError(ioutil.WriteFile(keyFile, key, 0600))
+
+			r, err := kubewebhookhttp.NewCertReloader(kubewebhookhttp.CertReloaderConfig{
+				CertFile:      certFile,
+				KeyFile:       keyFile,
+				CheckInterval: time.Nanosecond,
+			})
+			require.NoError(err)
+
+			// Update the files and make sure the modification time changes.
+			test.update(t, certFile, keyFile)
+			future := time.Now().Add(time.Minute)
+			_ = os.Chtimes(certFile, future, future)
+			_ = os.Chtimes(keyFile, future, future)
+
+			gotCert, err := r.TLSConfig().GetCertificate(nil)
+			require.NoError(err)
+			leaf, err := x509.ParseCertificate(gotCert.Certificate[0])
+			require.NoError(err)
+			assert.Equal(test.expCN, leaf.Subject.CommonName)
+		})
+	}
+}
+
+func TestCertReloaderKubernetesSecretRotation(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "kubewebhook-tls")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	// Kubernetes mounts the secrets as symlinks to a data directory (`..data`), on updates
+	// it writes the new data in a new directory and swaps the data directory symlink.
+	writeSecret := func(version, cn string, modTime time.Time) {
+		dataDir := filepath.Join(dir, version)
+		require.NoError(os.Mkdir(dataDir, 0700))
+		cert, key := newTestCert(t, cn)
+		require.NoError(ioutil.WriteFile(filepath.Join(dataDir, "tls.crt"), cert, 0600))
+		require.NoError(ioutil.WriteFile(filepath.Join(dataDir, "tls.key"), key, 0600))
+		require.NoError(os.Chtimes(filepath.Join(dataDir, "tls.crt"), modTime, modTime))
+		require.NoError(os.Chtimes(filepath.Join(dataDir, "tls.key"), modTime, modTime))
+
+		tmpLink := filepath.Join(dir, "..data_tmp")
+		require.NoError(os.Symlink(version, tmpLink))
+		require.NoError(os.Rename(tmpLink, filepath.Join(dir, "..data")))
+	}
+
+	writeSecret("..v1", "cert1", time.Now())
+	require.NoError(os.Symlink(filepath.Join("..data", "tls.crt"), filepath.Join(dir, "tls.crt")))
+	require.NoError(os.Symlink(filepath.Join("..data", "tls.key"), filepath.Join(dir, "tls.key")))
+
+	r, err := kubewebhookhttp.NewCertReloader(kubewebhookhttp.CertReloaderConfig{
+		CertFile:      filepath.Join(dir, "tls.crt"),
+		KeyFile:       filepath.Join(dir, "tls.key"),
+		CheckInterval: time.Nanosecond,
+	})
+	require.NoError(err)
+
+	getCN := func() string {
+		gotCert, err := r.GetCertificate(nil)
+		require.NoError(err)
+		leaf, err := x509.ParseCertificate(gotCert.Certificate[0])
+		require.NoError(err)
+		return leaf.Subject.CommonName
+	}
+
+	assert.Equal("cert1", getCN())
+	writeSecret("..v2", "cert2", time.Now().Add(time.Minute))
+	assert.Equal("cert2", getCN())
+}
+
+func TestNewCertReloaderInvalid(t *testing.T) {
+	tests := map[string]struct {
+		config kubewebhookhttp.CertReloaderConfig
+	}{
+		"Missing cert file should fail.": {
+			config: kubewebhookhttp.CertReloaderConfig{KeyFile: "tls.key"},
+		},
+
+		"Missing key file should fail.": {
+			config: kubewebhookhttp.CertReloaderConfig{CertFile: "tls.crt"},
+		},
+
+		"Not existing files should fail.": {
+			config: kubewebhookhttp.CertReloaderConfig{CertFile: "/tmp/kubewebhook-missing.crt", KeyFile: "/tmp/kubewebhook-missing.key"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := kubewebhookhttp.NewCertReloader(test.config)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package logr
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/go-logr/logr"
+
+	"github.com/slok/kubewebhook/v2/pkg/log"
+)
+
+type logger struct {
+	logger logr.Logger
+}
+
+// NewLogr returns a new log.Logger for a logr implementation (e.g zapr, klogr...).
+//
+// The warning messages will be logged as info messages and the debug messages as
+// verbosity 1 info messages.
+func NewLogr(l logr.Logger) log.Logger {
+	return logger{logger: l}
+}
+
+func (l logger) Infof(format string, args ...interface{}) {
+	l.logger.Info(fmt.Sprintf(format, args...))
+}
+
+func (l logger) Warningf(format string, args ...interface{}) {
+	l.logger.Info(fmt.Sprintf(format, args...))
+}
+
+func (l logger) Errorf(format string, args ...interface{}) {
+	l.logger.Error(nil, fmt.Sprintf(format, args...))
+}
+
+func (l logger) Debugf(format string, args ...interface{}) {
+	l.logger.V(1).Info(fmt.Sprintf(format, args...))
+}
+
+func (l logger) WithValues(kv log.Kv) log.Logger {
+	// Sort the keys so the logged values are deterministic.
+	keys := make([]string, 0, len(kv))
+	for k := range kv {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]interface{}, 0, len(kv)*2)
+	for _, k := range keys {
+		kvs = append(kvs, k, kv[k])
+	}
+
+	return NewLogr(l.logger.WithValues(kvs...))
+}
+
+func (l logger) WithCtxValues(ctx context.Context) log.Logger {
+	return l.WithValues(log.ValuesFromCtx(ctx))
+}
+
+func (l logger) SetValuesOnCtx(parent context.Context, values log.Kv) context.Context {
+	return log.CtxWithValues(parent, values)
+}
@@ -0,0 +1,103 @@
+package logr_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/kubewebhook/v2/pkg/log"
+	kwhlogr "github.com/slok/kubewebhook/v2/pkg/log/logr"
+)
+
+// recorderLogr is a logr.Logger that records the logged lines.
+type recorderLogr struct {
+	level  int
+	values []interface{}
+	lines  *[]string
+}
+
+func (r recorderLogr) Enabled() bool { return true }
+func (r recorderLogr) Info(msg string, kvs ...interface{}) {
+	*r.lines = append(*r.lines, fmt.Sprintf("info[%d] %s %v", r.level, msg, append(r.values, kvs...)))
+}
+func (r recorderLogr) Error(err error, msg string, kvs ...interface{}) {
+	*r.lines = append(*r.lines, fmt.Sprintf("error %s %v", msg, append(r.values, kvs...)))
+}
+func (r recorderLogr) V(level int) logr.Logger { r.level = level; return r }
+func (r recorderLogr) WithValues(kvs ...interface{}) logr.Logger {
+	r.values = append(append([]interface{}{}, r.values...), kvs...)
+	return r
+}
+func (r recorderLogr) WithName(name string) logr.Logger { return r }
+
+func TestLogr(t *testing.T) {
+	tests := map[string]struct {
+		log      func(l log.Logger)
+		expLines []string
+	}{
+		"Logging with the different levels should log on the correct logr levels.": {
+			log: func(l log.Logger) {
+				l.Infof("info %d", 1)
+				l.Warningf("warning %d", 2)
+				l.Errorf("error %d", 3)
+				l.Debugf("debug %d", 4)
+			},
+			expLines: []string{
+				"info[0] info 1 []",
+				"info[0] warning 2 []",
+				"error error 3 []",
+				"info[1] debug 4 []",
+			},
+		},
+
+		"Logging with values should log them as sorted structured key-value pairs.": {
+			log: func(l log.Logger) {
+				l.WithValues(log.Kv{"ns": "test-ns", "request-id": "1234", "kind": "Pod"}).Infof("test")
+			},
+			expLines: []string{
+				"info[0] test [kind Pod ns test-ns request-id 1234]",
+			},
+		},
+
+		"Logging with context values should log them as structured key-value pairs.": {
+			log: func(l log.Logger) {
+				ctx := l.SetValuesOnCtx(context.TODO(), log.Kv{"request-id": "1234"})
+				l.WithValues(log.Kv{"svc": "test"}).WithCtxValues(ctx).Warningf("test")
+			},
+			expLines: []string{
+				"info[0] test [svc test request-id 1234]",
+			},
+		},
+
+		"Logging with the review request context values should log the request fields as structured key-value pairs.": {
+			log: func(l log.Logger) {
+				ctx := l.SetValuesOnCtx(context.TODO(), log.Kv{
+					"request-id": "1234",
+					"op":         "create",
+					"kind":       "v1/Pod",
+					"ns":         "test-ns",
+					"name":       "test-pod",
+				})
+				l.WithCtxValues(ctx).Debugf("Admission review request received")
+			},
+			expLines: []string{
+				"info[1] Admission review request received [kind v1/Pod name test-pod ns test-ns op create request-id 1234]",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			lines := []string{}
+			l := kwhlogr.NewLogr(recorderLogr{lines: &lines})
+			test.log(l)
+
+			assert.Equal(test.expLines, lines)
+		})
+	}
+}
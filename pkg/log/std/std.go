@@ -0,0 +1,101 @@
+package std
+
+import (
+	"context"
+	"fmt"
+	stdlog "log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/slok/kubewebhook/v2/pkg/log"
+)
+
+// Level is the minimum level of the messages that will be logged.
+type Level int
+
+const (
+	// LevelDebug logs all the messages.
+	LevelDebug Level = iota
+	// LevelInfo logs info, warning and error messages.
+	LevelInfo
+	// LevelWarning logs warning and error messages.
+	LevelWarning
+	// LevelError logs only error messages.
+	LevelError
+)
+
+type logger struct {
+	logger *stdlog.Logger
+	level  Level
+	values log.Kv
+}
+
+// NewStd returns a new log.Logger for a Go standard library logger, the messages below the
+// level will not be logged. If the logger is `nil` it will log on stderr.
+//
+// The values will be logged after the message as sorted `key=value` pairs.
+func NewStd(l *stdlog.Logger, level Level) log.Logger {
+	if l == nil {
+		l = stdlog.New(os.Stderr, "", stdlog.LstdFlags)
+	}
+
+	return logger{logger: l, level: level, values: log.Kv{}}
+}
+
+func (l logger) Infof(format string, args ...interface{}) {
+	l.logf(LevelInfo, "INFO", format, args...)
+}
+
+func (l logger) Warningf(format string, args ...interface{}) {
+	l.logf(LevelWarning, "WARN", format, args...)
+}
+
+func (l logger) Errorf(format string, args ...interface{}) {
+	l.logf(LevelError, "ERROR", format, args...)
+}
+
+func (l logger) Debugf(format string, args ...interface{}) {
+	l.logf(LevelDebug, "DEBUG", format, args...)
+}
+
+func (l logger) logf(level Level, prefix, format string, args ...interface{}) {
+	if level < l.level {
+		return
+	}
+
+	// Sort the keys so the logged values are deterministic.
+	keys := make([]string, 0, len(l.values))
+	for k := range l.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "[%s] %s", prefix, fmt.Sprintf(format, args...))
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%v", k, l.values[k])
+	}
+
+	l.logger.Print(b.String())
+}
+
+func (l logger) WithValues(kv log.Kv) log.Logger {
+	values := log.Kv{}
+	for k, v := range l.values {
+		values[k] = v
+	}
+	for k, v := range kv {
+		values[k] = v
+	}
+
+	return logger{logger: l.logger, level: l.level, values: values}
+}
+
+func (l logger) WithCtxValues(ctx context.Context) log.Logger {
+	return l.WithValues(log.ValuesFromCtx(ctx))
+}
+
+func (l logger) SetValuesOnCtx(parent context.Context, values log.Kv) context.Context {
+	return log.CtxWithValues(parent, values)
+}
@@ -0,0 +1,72 @@
+package std_test
+
+import (
+	"bytes"
+	"context"
+	stdlog "log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/kubewebhook/v2/pkg/log"
+	kwhstd "github.com/slok/kubewebhook/v2/pkg/log/std"
+)
+
+func TestStd(t *testing.T) {
+	tests := map[string]struct {
+		level  kwhstd.Level
+		log    func(l log.Logger)
+		expOut string
+	}{
+		"Logging with debug level should log all the messages.": {
+			level: kwhstd.LevelDebug,
+			log: func(l log.Logger) {
+				l.Debugf("debug %d", 1)
+				l.Infof("info %d", 2)
+				l.Warningf("warning %d", 3)
+				l.Errorf("error %d", 4)
+			},
+			expOut: "[DEBUG] debug 1\n[INFO] info 2\n[WARN] warning 3\n[ERROR] error 4\n",
+		},
+
+		"Logging with warning level should only log warning and error messages.": {
+			level: kwhstd.LevelWarning,
+			log: func(l log.Logger) {
+				l.Debugf("debug %d", 1)
+				l.Infof("info %d", 2)
+				l.Warningf("warning %d", 3)
+				l.Errorf("error %d", 4)
+			},
+			expOut: "[WARN] warning 3\n[ERROR] error 4\n",
+		},
+
+		"Logging with values should log them as sorted key-value pairs.": {
+			level: kwhstd.LevelInfo,
+			log: func(l log.Logger) {
+				l.WithValues(log.Kv{"ns": "test-ns", "kind": "Pod"}).Infof("test")
+			},
+			expOut: "[INFO] test kind=Pod ns=test-ns\n",
+		},
+
+		"Logging with context values should log them as key-value pairs.": {
+			level: kwhstd.LevelInfo,
+			log: func(l log.Logger) {
+				ctx := l.SetValuesOnCtx(context.TODO(), log.Kv{"request-id": "1234"})
+				l.WithValues(log.Kv{"svc": "test"}).WithCtxValues(ctx).Errorf("test")
+			},
+			expOut: "[ERROR] test request-id=1234 svc=test\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var out bytes.Buffer
+			l := kwhstd.NewStd(stdlog.New(&out, "", 0), test.level)
+			test.log(l)
+
+			assert.Equal(test.expOut, out.String())
+		})
+	}
+}
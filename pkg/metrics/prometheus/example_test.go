@@ -0,0 +1,98 @@
+package prometheus_test
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kwhhttp "github.com/slok/kubewebhook/v2/pkg/http"
+	metrics "github.com/slok/kubewebhook/v2/pkg/metrics/prometheus"
+	"github.com/slok/kubewebhook/v2/pkg/model"
+	kwhopentracing "github.com/slok/kubewebhook/v2/pkg/tracing/opentracing"
+	"github.com/slok/kubewebhook/v2/pkg/webhook"
+	"github.com/slok/kubewebhook/v2/pkg/webhook/validating"
+)
+
+// MeasuredWebhook shows how you would measure a webhook with Prometheus and expose the
+// metrics, review durations (and counts) will be measured by webhook, operation, resource
+// and result (allowed, mutated and success).
+func ExampleRecorder_measuredWebhook() {
+	reg := prometheus.NewRegistry()
+	rec, _ := metrics.NewRecorder(metrics.RecorderConfig{Registry: reg})
+
+	// Create our webhook.
+	val := validating.ValidatorFunc(func(_ context.Context, _ *model.AdmissionReview, _ metav1.Object) (*validating.ValidatorResult, error) {
+		return &validating.ValidatorResult{Valid: true}, nil
+	})
+	wh, _ := validating.NewWebhook(validating.WebhookConfig{
+		ID:        "measuredWebhook",
+		Validator: val,
+	})
+
+	// Wrap our webhook with the metrics and serve it along with the metrics.
+	mux := http.NewServeMux()
+	mux.Handle("/validate", kwhhttp.MustHandlerFor(kwhhttp.HandlerConfig{Webhook: webhook.NewMeasuredWebhook(rec, wh)}))
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+
+	_ = http.ListenAndServe(":8080", mux)
+}
+
+// traceIDFromContext gets the trace ID of the context trace from the OpenTracing span context. The
+// span context is tracer specific, this example uses the OpenTracing mock tracer, with Jaeger it would
+// be `jaeger.SpanContext` and `TraceID().String()`.
+func traceIDFromContext(ctx context.Context) string {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return ""
+	}
+
+	sc, ok := span.Context().(mocktracer.MockSpanContext)
+	if !ok {
+		return ""
+	}
+
+	return strconv.Itoa(sc.TraceID)
+}
+
+// Exemplars shows how you would link the review duration metrics with the review traces
+// using exemplars.
+func ExampleRecorder_exemplars() {
+	reg := prometheus.NewRegistry()
+	rec, _ := metrics.NewRecorder(metrics.RecorderConfig{
+		Registry: reg,
+		ExemplarFromContext: func(ctx context.Context) prometheus.Labels {
+			traceID := traceIDFromContext(ctx)
+			if traceID == "" {
+				return nil
+			}
+			return prometheus.Labels{"trace_id": traceID}
+		},
+	})
+
+	// Create our webhook.
+	val := validating.ValidatorFunc(func(_ context.Context, _ *model.AdmissionReview, _ metav1.Object) (*validating.ValidatorResult, error) {
+		return &validating.ValidatorResult{Valid: true}, nil
+	})
+	wh, _ := validating.NewWebhook(validating.WebhookConfig{
+		ID:        "exemplarsWebhook",
+		Validator: val,
+	})
+
+	// The metrics are measured with the context of the review, so the review needs to be traced
+	// before measuring it (e.g the HTTP handler tracer or the tracing middleware).
+	tracer := kwhopentracing.NewTracer(mocktracer.New())
+	wh = webhook.Chain(wh, webhook.TracingMiddleware(tracer), webhook.MetricsMiddleware(rec))
+
+	// Exemplars are only exposed using OpenMetrics format.
+	mux := http.NewServeMux()
+	mux.Handle("/validate", kwhhttp.MustHandlerFor(kwhhttp.HandlerConfig{Webhook: wh}))
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{EnableOpenMetrics: true}))
+
+	_ = http.ListenAndServe(":8080", mux)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/slok/kubewebhook/v2/pkg/webhook"
@@ -17,6 +18,22 @@ const (
 type RecorderConfig struct {
 	Registry        prometheus.Registerer
 	ReviewOpBuckets []float64
+	// ReviewTimeoutRatioBuckets are the buckets of the ratio between the review duration
+	// and the review timeout.
+	ReviewTimeoutRatioBuckets []float64
+	// NearTimeoutRatio is the ratio of the review timeout that, when exceeded by the review
+	// duration, will count the review as near timeout. Only the reviews that have a
+	// timeout (deadline on the context) are measured. By default 0.8.
+	NearTimeoutRatio float64
+	// IncludeNamespaceLabel will add the namespace of the reviewed resource as the
+	// `resource_namespace` label of the metrics. Disabled by default because on clusters
+	// with lots of namespaces the cardinality of the metrics can be very high.
+	IncludeNamespaceLabel bool
+	// ExemplarFromContext returns the exemplar labels (e.g the trace ID) that will be recorded
+	// with the review duration measurements, using the context of the measured review. The
+	// exemplars are only exposed using the OpenMetrics format (`promhttp.HandlerOpts.EnableOpenMetrics`).
+	// By default it will not record exemplars.
+	ExemplarFromContext func(ctx context.Context) prometheus.Labels
 }
 
 func (c *RecorderConfig) defaults() error {
@@ -28,15 +45,33 @@ func (c *RecorderConfig) defaults() error {
 		c.ReviewOpBuckets = prometheus.DefBuckets
 	}
 
+	if c.ReviewTimeoutRatioBuckets == nil {
+		c.ReviewTimeoutRatioBuckets = []float64{.1, .25, .5, .75, .8, .9, 1}
+	}
+
+	if c.NearTimeoutRatio == 0 {
+		c.NearTimeoutRatio = 0.8
+	}
+
+	if c.NearTimeoutRatio < 0 || c.NearTimeoutRatio > 1 {
+		return fmt.Errorf("near timeout ratio must be between 0 and 1")
+	}
+
 	return nil
 }
 
 // Recorder knows how to measure the metrics of the library using Prometheus
 // as the backend for the measurements.
 type Recorder struct {
-	webhookValReviewDuration *prometheus.HistogramVec
-	webhookMutReviewDuration *prometheus.HistogramVec
-	webhookReviewWarnings    *prometheus.CounterVec
+	webhookValReviewDuration  *prometheus.HistogramVec
+	webhookMutReviewDuration  *prometheus.HistogramVec
+	webhookReviewWarnings     *prometheus.CounterVec
+	webhookReviewTimeoutRatio *prometheus.HistogramVec
+	webhookReviewNearTimeouts *prometheus.CounterVec
+
+	nearTimeoutRatio      float64
+	includeNamespaceLabel bool
+	exemplarFromContext   func(ctx context.Context) prometheus.Labels
 }
 
 // NewRecorder returns a new Prometheus metrics recorder.
@@ -46,6 +81,13 @@ func NewRecorder(config RecorderConfig) (*Recorder, error) {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
+	commonLabels := []string{"webhook_id", "webhook_version", "resource_kind", "operation", "dry_run", "success"}
+	if config.IncludeNamespaceLabel {
+		commonLabels = append(commonLabels, "resource_namespace")
+	}
+	// Avoid sharing the underlying array between the appended labels.
+	commonLabels = commonLabels[:len(commonLabels):len(commonLabels)]
+
 	r := &Recorder{
 		webhookValReviewDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
 			Namespace: prefix,
@@ -53,7 +95,7 @@ func NewRecorder(config RecorderConfig) (*Recorder, error) {
 			Name:      "review_duration_seconds",
 			Help:      "The duration of the admission review handled by a validating webhook.",
 			Buckets:   config.ReviewOpBuckets,
-		}, []string{"webhook_id", "webhook_version", "resource_namespace", "resource_kind", "operation", "dry_run", "success", "allowed"}),
+		}, append(commonLabels, "allowed")),
 
 		webhookMutReviewDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
 			Namespace: prefix,
@@ -61,14 +103,33 @@ func NewRecorder(config RecorderConfig) (*Recorder, error) {
 			Name:      "review_duration_seconds",
 			Help:      "The duration of the admission review handled by a mutating webhook.",
 			Buckets:   config.ReviewOpBuckets,
-		}, []string{"webhook_id", "webhook_version", "resource_namespace", "resource_kind", "operation", "dry_run", "success", "mutated"}),
+		}, append(commonLabels, "mutated")),
 
 		webhookReviewWarnings: prometheus.NewCounterVec(prometheus.CounterOpts{
 			Namespace: prefix,
 			Subsystem: "webhook",
 			Name:      "review_warnings_total",
 			Help:      "The total number warnings the webhooks are returning on the review process.",
-		}, []string{"webhook_id", "webhook_version", "resource_namespace", "resource_kind", "operation", "dry_run", "success"}),
+		}, commonLabels),
+
+		webhookReviewTimeoutRatio: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: prefix,
+			Subsystem: "webhook",
+			Name:      "review_timeout_ratio",
+			Help:      "The ratio of the review timeout used by the webhook reviews.",
+			Buckets:   config.ReviewTimeoutRatioBuckets,
+		}, commonLabels),
+
+		webhookReviewNearTimeouts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: prefix,
+			Subsystem: "webhook",
+			Name:      "review_near_timeout_total",
+			Help:      "The total number of webhook reviews that used more than the near timeout ratio of the review timeout.",
+		}, commonLabels),
+
+		nearTimeoutRatio:      config.NearTimeoutRatio,
+		includeNamespaceLabel: config.IncludeNamespaceLabel,
+		exemplarFromContext:   config.ExemplarFromContext,
 	}
 
 	// Register our metrics on the received recorder.
@@ -76,6 +137,8 @@ func NewRecorder(config RecorderConfig) (*Recorder, error) {
 		r.webhookValReviewDuration,
 		r.webhookMutReviewDuration,
 		r.webhookReviewWarnings,
+		r.webhookReviewTimeoutRatio,
+		r.webhookReviewNearTimeouts,
 	)
 
 	return r, nil
@@ -84,53 +147,77 @@ func NewRecorder(config RecorderConfig) (*Recorder, error) {
 var _ webhook.MetricsRecorder = Recorder{}
 
 // MeasureValidatingWebhookReviewOp measures a validating webhook review operation on Prometheus.
-func (r Recorder) MeasureValidatingWebhookReviewOp(_ context.Context, data webhook.MeasureValidatingOpData) {
+func (r Recorder) MeasureValidatingWebhookReviewOp(ctx context.Context, data webhook.MeasureValidatingOpData) {
 	// Measure Operation.
-	r.webhookValReviewDuration.With(prometheus.Labels{
-		"webhook_id":         data.WebhookID,
-		"webhook_version":    data.AdmissionReviewVersion,
-		"resource_namespace": data.ResourceNamespace,
-		"resource_kind":      data.ResourceKind,
-		"operation":          data.Operation,
-		"dry_run":            strconv.FormatBool(data.DryRun),
-		"success":            strconv.FormatBool(data.Success),
-		"allowed":            strconv.FormatBool(data.Allowed),
-	}).Observe(data.Duration.Seconds())
+	opLabels := r.commonLabels(data.MeasureOpCommonData)
+	opLabels["allowed"] = strconv.FormatBool(data.Allowed)
+	r.observeDuration(ctx, r.webhookValReviewDuration.With(opLabels), data.Duration)
 
 	// Measure warnings.
-	r.webhookReviewWarnings.With(prometheus.Labels{
-		"webhook_id":         data.WebhookID,
-		"webhook_version":    data.AdmissionReviewVersion,
-		"resource_namespace": data.ResourceNamespace,
-		"resource_kind":      data.ResourceKind,
-		"operation":          data.Operation,
-		"dry_run":            strconv.FormatBool(data.DryRun),
-		"success":            strconv.FormatBool(data.Success),
-	}).Add(float64(data.WarningsNumber))
+	r.webhookReviewWarnings.With(r.commonLabels(data.MeasureOpCommonData)).Add(float64(data.WarningsNumber))
+
+	r.measureTimeout(data.MeasureOpCommonData)
 }
 
 // MeasureMutatingWebhookReviewOp measures a mutating webhook review operation on Prometheus.
-func (r Recorder) MeasureMutatingWebhookReviewOp(_ context.Context, data webhook.MeasureMutatingOpData) {
+func (r Recorder) MeasureMutatingWebhookReviewOp(ctx context.Context, data webhook.MeasureMutatingOpData) {
 	// Measure operation.
-	r.webhookMutReviewDuration.With(prometheus.Labels{
-		"webhook_id":         data.WebhookID,
-		"webhook_version":    data.AdmissionReviewVersion,
-		"resource_namespace": data.ResourceNamespace,
-		"resource_kind":      data.ResourceKind,
-		"operation":          data.Operation,
-		"dry_run":            strconv.FormatBool(data.DryRun),
-		"success":            strconv.FormatBool(data.Success),
-		"mutated":            strconv.FormatBool(data.Mutated),
-	}).Observe(data.Duration.Seconds())
+	opLabels := r.commonLabels(data.MeasureOpCommonData)
+	opLabels["mutated"] = strconv.FormatBool(data.Mutated)
+	r.observeDuration(ctx, r.webhookMutReviewDuration.With(opLabels), data.Duration)
 
 	// Measure warnings.
-	r.webhookReviewWarnings.With(prometheus.Labels{
-		"webhook_id":         data.WebhookID,
-		"webhook_version":    data.AdmissionReviewVersion,
-		"resource_namespace": data.ResourceNamespace,
-		"resource_kind":      data.ResourceKind,
-		"operation":          data.Operation,
-		"dry_run":            strconv.FormatBool(data.DryRun),
-		"success":            strconv.FormatBool(data.Success),
-	}).Add(float64(data.WarningsNumber))
+	r.webhookReviewWarnings.With(r.commonLabels(data.MeasureOpCommonData)).Add(float64(data.WarningsNumber))
+
+	r.measureTimeout(data.MeasureOpCommonData)
+}
+
+// observeDuration observes the review duration with the context exemplar if there is one.
+func (r Recorder) observeDuration(ctx context.Context, obs prometheus.Observer, duration time.Duration) {
+	if r.exemplarFromContext != nil {
+		if exemplar := r.exemplarFromContext(ctx); len(exemplar) > 0 {
+			if eobs, ok := obs.(prometheus.ExemplarObserver); ok {
+				eobs.ObserveWithExemplar(duration.Seconds(), exemplar)
+				return
+			}
+		}
+	}
+
+	obs.Observe(duration.Seconds())
+}
+
+// measureTimeout measures how close the review has been to its timeout. The reviews without
+// timeout are ignored.
+func (r Recorder) measureTimeout(data webhook.MeasureOpCommonData) {
+	if data.Timeout <= 0 {
+		return
+	}
+
+	labels := r.commonLabels(data)
+	ratio := data.Duration.Seconds() / data.Timeout.Seconds()
+	r.webhookReviewTimeoutRatio.With(labels).Observe(ratio)
+
+	// Always initialize the counter, this way we can alert on rates.
+	nearTimeouts := r.webhookReviewNearTimeouts.With(labels)
+	if ratio >= r.nearTimeoutRatio {
+		nearTimeouts.Inc()
+	}
+}
+
+// commonLabels returns the labels shared by all the review metrics.
+func (r Recorder) commonLabels(data webhook.MeasureOpCommonData) prometheus.Labels {
+	labels := prometheus.Labels{
+		"webhook_id":      data.WebhookID,
+		"webhook_version": data.AdmissionReviewVersion,
+		"resource_kind":   data.ResourceKind,
+		"operation":       data.Operation,
+		"dry_run":         strconv.FormatBool(data.DryRun),
+		"success":         strconv.FormatBool(data.Success),
+	}
+
+	if r.includeNamespaceLabel {
+		labels["resource_namespace"] = data.ResourceNamespace
+	}
+
+	return labels
 }
@@ -34,11 +34,13 @@ func getCommonData() webhook.MeasureOpCommonData {
 
 func TestRecorder(t *testing.T) {
 	tests := map[string]struct {
-		config     metrics.RecorderConfig
-		measure    func(r *metrics.Recorder)
-		expMetrics []string
+		config            metrics.RecorderConfig
+		measure           func(r *metrics.Recorder)
+		expMetrics        []string
+		expMissingMetrics []string
 	}{
 		"Measure validation webhook review.": {
+			config: metrics.RecorderConfig{IncludeNamespaceLabel: true},
 			measure: func(r *metrics.Recorder) {
 				c1 := getCommonData()
 				c2 := getCommonData()
@@ -88,6 +90,7 @@ func TestRecorder(t *testing.T) {
 		},
 
 		"Measure mutating webhook review.": {
+			config: metrics.RecorderConfig{IncludeNamespaceLabel: true},
 			measure: func(r *metrics.Recorder) {
 				c1 := getCommonData()
 				c2 := getCommonData()
@@ -135,6 +138,73 @@ func TestRecorder(t *testing.T) {
 				`kubewebhook_webhook_review_warnings_total{dry_run="true",operation="delete",resource_kind="core/v1/Pod",resource_namespace="test-ns",success="false",webhook_id="test-wh",webhook_version="v1"} 5`,
 			},
 		},
+
+		"Measure webhook reviews near timeout.": {
+			config: metrics.RecorderConfig{IncludeNamespaceLabel: true},
+			measure: func(r *metrics.Recorder) {
+				c1 := getCommonData()
+				c1.Timeout = 50 * time.Millisecond
+				c2 := getCommonData()
+				c2.WebhookID = "test2-wh"
+				c2.Duration = 3 * time.Second
+				c2.Timeout = 10 * time.Second
+				c3 := getCommonData()
+				c3.WebhookID = "test3-wh"
+				r.MeasureValidatingWebhookReviewOp(context.TODO(), webhook.MeasureValidatingOpData{MeasureOpCommonData: c1, Allowed: true})
+				r.MeasureMutatingWebhookReviewOp(context.TODO(), webhook.MeasureMutatingOpData{MeasureOpCommonData: c2, Mutated: true})
+				r.MeasureMutatingWebhookReviewOp(context.TODO(), webhook.MeasureMutatingOpData{MeasureOpCommonData: c3, Mutated: true})
+			},
+			expMetrics: []string{
+				`# HELP kubewebhook_webhook_review_timeout_ratio The ratio of the review timeout used by the webhook reviews.`,
+				`# TYPE kubewebhook_webhook_review_timeout_ratio histogram`,
+				`kubewebhook_webhook_review_timeout_ratio_bucket{dry_run="true",operation="delete",resource_kind="core/v1/Pod",resource_namespace="test-ns",success="false",webhook_id="test-wh",webhook_version="v1",le="0.8"} 0`,
+				`kubewebhook_webhook_review_timeout_ratio_bucket{dry_run="true",operation="delete",resource_kind="core/v1/Pod",resource_namespace="test-ns",success="false",webhook_id="test-wh",webhook_version="v1",le="0.9"} 1`,
+				`kubewebhook_webhook_review_timeout_ratio_count{dry_run="true",operation="delete",resource_kind="core/v1/Pod",resource_namespace="test-ns",success="false",webhook_id="test-wh",webhook_version="v1"} 1`,
+				`kubewebhook_webhook_review_timeout_ratio_bucket{dry_run="true",operation="delete",resource_kind="core/v1/Pod",resource_namespace="test-ns",success="false",webhook_id="test2-wh",webhook_version="v1",le="0.25"} 0`,
+				`kubewebhook_webhook_review_timeout_ratio_bucket{dry_run="true",operation="delete",resource_kind="core/v1/Pod",resource_namespace="test-ns",success="false",webhook_id="test2-wh",webhook_version="v1",le="0.5"} 1`,
+				`kubewebhook_webhook_review_timeout_ratio_count{dry_run="true",operation="delete",resource_kind="core/v1/Pod",resource_namespace="test-ns",success="false",webhook_id="test2-wh",webhook_version="v1"} 1`,
+
+				`# HELP kubewebhook_webhook_review_near_timeout_total The total number of webhook reviews that used more than the near timeout ratio of the review timeout.`,
+				`# TYPE kubewebhook_webhook_review_near_timeout_total counter`,
+				`kubewebhook_webhook_review_near_timeout_total{dry_run="true",operation="delete",resource_kind="core/v1/Pod",resource_namespace="test-ns",success="false",webhook_id="test-wh",webhook_version="v1"} 1`,
+				`kubewebhook_webhook_review_near_timeout_total{dry_run="true",operation="delete",resource_kind="core/v1/Pod",resource_namespace="test-ns",success="false",webhook_id="test2-wh",webhook_version="v1"} 0`,
+			},
+			expMissingMetrics: []string{
+				`kubewebhook_webhook_review_timeout_ratio_count{dry_run="true",operation="delete",resource_kind="core/v1/Pod",resource_namespace="test-ns",success="false",webhook_id="test3-wh",webhook_version="v1"}`,
+				`kubewebhook_webhook_review_near_timeout_total{dry_run="true",operation="delete",resource_kind="core/v1/Pod",resource_namespace="test-ns",success="false",webhook_id="test3-wh",webhook_version="v1"}`,
+			},
+		},
+
+		"Measure webhook reviews near timeout with a custom near timeout ratio.": {
+			config: metrics.RecorderConfig{NearTimeoutRatio: 0.9, IncludeNamespaceLabel: true},
+			measure: func(r *metrics.Recorder) {
+				c1 := getCommonData()
+				c1.Timeout = 50 * time.Millisecond
+				r.MeasureValidatingWebhookReviewOp(context.TODO(), webhook.MeasureValidatingOpData{MeasureOpCommonData: c1, Allowed: true})
+			},
+			expMetrics: []string{
+				`kubewebhook_webhook_review_near_timeout_total{dry_run="true",operation="delete",resource_kind="core/v1/Pod",resource_namespace="test-ns",success="false",webhook_id="test-wh",webhook_version="v1"} 0`,
+			},
+		},
+
+		"Measure webhook reviews without the namespace label by default.": {
+			measure: func(r *metrics.Recorder) {
+				c1 := getCommonData()
+				c1.Timeout = 50 * time.Millisecond
+				r.MeasureValidatingWebhookReviewOp(context.TODO(), webhook.MeasureValidatingOpData{MeasureOpCommonData: c1, Allowed: true})
+				r.MeasureMutatingWebhookReviewOp(context.TODO(), webhook.MeasureMutatingOpData{MeasureOpCommonData: c1, Mutated: true})
+			},
+			expMetrics: []string{
+				`kubewebhook_validating_webhook_review_duration_seconds_count{allowed="true",dry_run="true",operation="delete",resource_kind="core/v1/Pod",success="false",webhook_id="test-wh",webhook_version="v1"} 1`,
+				`kubewebhook_mutating_webhook_review_duration_seconds_count{dry_run="true",mutated="true",operation="delete",resource_kind="core/v1/Pod",success="false",webhook_id="test-wh",webhook_version="v1"} 1`,
+				`kubewebhook_webhook_review_warnings_total{dry_run="true",operation="delete",resource_kind="core/v1/Pod",success="false",webhook_id="test-wh",webhook_version="v1"} 10`,
+				`kubewebhook_webhook_review_timeout_ratio_count{dry_run="true",operation="delete",resource_kind="core/v1/Pod",success="false",webhook_id="test-wh",webhook_version="v1"} 2`,
+				`kubewebhook_webhook_review_near_timeout_total{dry_run="true",operation="delete",resource_kind="core/v1/Pod",success="false",webhook_id="test-wh",webhook_version="v1"} 2`,
+			},
+			expMissingMetrics: []string{
+				`resource_namespace`,
+			},
+		},
 	}
 
 	for name, test := range tests {
@@ -155,6 +225,72 @@ func TestRecorder(t *testing.T) {
 			allMetrics, err := ioutil.ReadAll(w.Result().Body)
 			require.NoError(err)
 
+			// Check metrics.
+			for _, expMetric := range test.expMetrics {
+				assert.Contains(string(allMetrics), expMetric)
+			}
+			for _, expMissingMetric := range test.expMissingMetrics {
+				assert.NotContains(string(allMetrics), expMissingMetric)
+			}
+		})
+	}
+}
+
+type traceIDKey struct{}
+
+func TestRecorderExemplars(t *testing.T) {
+	tests := map[string]struct {
+		ctx        context.Context
+		expMetrics []string
+	}{
+		"Measuring a review with a trace ID on the context should record the trace ID exemplar.": {
+			ctx: context.WithValue(context.TODO(), traceIDKey{}, "1234"),
+			expMetrics: []string{
+				`kubewebhook_validating_webhook_review_duration_seconds_bucket{allowed="true",dry_run="true",operation="delete",resource_kind="core/v1/Pod",success="false",webhook_id="test-wh",webhook_version="v1",le="0.05"} 1 # {trace_id="1234"} 0.042`,
+				`kubewebhook_mutating_webhook_review_duration_seconds_bucket{dry_run="true",mutated="true",operation="delete",resource_kind="core/v1/Pod",success="false",webhook_id="test-wh",webhook_version="v1",le="0.05"} 1 # {trace_id="1234"} 0.042`,
+			},
+		},
+
+		"Measuring a review without a trace ID on the context should not record exemplars.": {
+			ctx: context.TODO(),
+			expMetrics: []string{
+				`kubewebhook_validating_webhook_review_duration_seconds_bucket{allowed="true",dry_run="true",operation="delete",resource_kind="core/v1/Pod",success="false",webhook_id="test-wh",webhook_version="v1",le="0.05"} 1` + "\n",
+				`kubewebhook_mutating_webhook_review_duration_seconds_bucket{dry_run="true",mutated="true",operation="delete",resource_kind="core/v1/Pod",success="false",webhook_id="test-wh",webhook_version="v1",le="0.05"} 1` + "\n",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			reg := prometheus.NewRegistry()
+			rec, err := metrics.NewRecorder(metrics.RecorderConfig{
+				Registry: reg,
+				ExemplarFromContext: func(ctx context.Context) prometheus.Labels {
+					traceID, _ := ctx.Value(traceIDKey{}).(string)
+					if traceID == "" {
+						return nil
+					}
+					return prometheus.Labels{"trace_id": traceID}
+				},
+			})
+			require.NoError(err)
+
+			c := getCommonData()
+			rec.MeasureValidatingWebhookReviewOp(test.ctx, webhook.MeasureValidatingOpData{MeasureOpCommonData: c, Allowed: true})
+			rec.MeasureMutatingWebhookReviewOp(test.ctx, webhook.MeasureMutatingOpData{MeasureOpCommonData: c, Mutated: true})
+
+			// Exemplars are only exposed with OpenMetrics format.
+			h := promhttp.HandlerFor(reg, promhttp.HandlerOpts{EnableOpenMetrics: true})
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", "/metrics", nil)
+			r.Header.Set("Accept", "application/openmetrics-text; version=0.0.1")
+			h.ServeHTTP(w, r)
+			allMetrics, err := ioutil.ReadAll(w.Result().Body)
+			require.NoError(err)
+
 			// Check metrics.
 			for _, expMetric := range test.expMetrics {
 				assert.Contains(string(allMetrics), expMetric)
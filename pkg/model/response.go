@@ -10,19 +10,48 @@ type AdmissionResponse interface {
 type ValidatingAdmissionResponse struct {
 	admissionResponse
 
-	ID       string
-	Allowed  bool
-	Message  string
+	// ID is the ID of the admission review.
+	ID string
+	// Allowed tells if the resource is allowed or not.
+	Allowed bool
+	// Message is the reason shown to the user when the resource is not allowed
+	// (e.g: `admission webhook "x" denied the request: {Message}`).
+	Message string
+	// StatusCode is the status code of the result when the resource is not allowed,
+	// by default 400.
+	StatusCode int32
+	// Violations are the field violations of the resource when is not allowed, these will
+	// be returned as the causes of the result status.
+	Violations []Violation
+	// Warnings are the warnings shown to the user, only on `v1` admission reviews.
 	Warnings []string
+	// AuditAnnotations are the annotations added to the apiserver audit event of the request.
+	AuditAnnotations map[string]string
+}
+
+// Violation is the violation of a field of a resource that is not valid.
+type Violation struct {
+	// Field is the path of the field that is not valid (e.g: `spec.containers[0].image`).
+	Field string
+	// Message is the reason of the violation.
+	Message string
 }
 
 // MutatingAdmissionResponse is the response for mutating webhooks.
+//
+// The mutation is always returned as a JSON patch (RFC 6902), Kubernetes admission doesn't
+// support other patch types (e.g JSON merge patch) on the admission review responses.
 type MutatingAdmissionResponse struct {
 	admissionResponse
 
-	ID             string
+	// ID is the ID of the admission review.
+	ID string
+	// JSONPatchPatch is the JSON patch with the mutation, empty if there is no mutation.
 	JSONPatchPatch []byte
-	Warnings       []string
+	// Warnings are the warnings shown to the user, only on `v1` admission reviews.
+	Warnings []string
+	// AuditAnnotations are the annotations added to the apiserver audit event of the request.
+	AuditAnnotations map[string]string
 }
 
 // Helper type to satisfiy the AdmissionResponse sealed interface.
@@ -3,6 +3,7 @@ package model
 import (
 	admissionv1 "k8s.io/api/admission/v1"
 	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	authenticationv1 "k8s.io/api/authentication/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -45,9 +46,11 @@ type AdmissionReview struct {
 	Version      AdmissionReviewVersion
 	RequestGVR   *metav1.GroupVersionResource
 	RequestGVK   *metav1.GroupVersionKind
+	SubResource  string
 	OldObjectRaw []byte
 	NewObjectRaw []byte
 	DryRun       bool
+	UserInfo     authenticationv1.UserInfo
 }
 
 // NewAdmissionReviewV1Beta1 returns a new AdmissionReview from a admission/v1beta/admissionReview.
@@ -67,9 +70,11 @@ func NewAdmissionReviewV1Beta1(ar *admissionv1beta1.AdmissionReview) AdmissionRe
 		Operation:               v1Beta1OperationToModel(ar.Request.Operation),
 		OldObjectRaw:            ar.Request.OldObject.Raw,
 		NewObjectRaw:            ar.Request.Object.Raw,
-		RequestGVR:              ar.Request.RequestResource,
-		RequestGVK:              ar.Request.RequestKind,
+		RequestGVR:              requestGVR(ar.Request.RequestResource, ar.Request.Resource),
+		RequestGVK:              requestGVK(ar.Request.RequestKind, ar.Request.Kind),
+		SubResource:             ar.Request.SubResource,
 		DryRun:                  dryRun,
+		UserInfo:                ar.Request.UserInfo,
 	}
 }
 
@@ -105,9 +110,11 @@ func NewAdmissionReviewV1(ar *admissionv1.AdmissionReview) AdmissionReview {
 		Operation:               v1OperationToModel(ar.Request.Operation),
 		OldObjectRaw:            ar.Request.OldObject.Raw,
 		NewObjectRaw:            ar.Request.Object.Raw,
-		RequestGVR:              ar.Request.RequestResource,
-		RequestGVK:              ar.Request.RequestKind,
+		RequestGVR:              requestGVR(ar.Request.RequestResource, ar.Request.Resource),
+		RequestGVK:              requestGVK(ar.Request.RequestKind, ar.Request.Kind),
+		SubResource:             ar.Request.SubResource,
 		DryRun:                  dryRun,
+		UserInfo:                ar.Request.UserInfo,
 	}
 }
 
@@ -125,3 +132,23 @@ func v1OperationToModel(op admissionv1.Operation) AdmissionReviewOp {
 
 	return OperationUnknown
 }
+
+// requestGVK returns the GVK of the original request, old apiservers (<1.15) don't
+// set `requestKind` on v1beta1 reviews, in that case we fallback to `kind`.
+func requestGVK(requestKind *metav1.GroupVersionKind, kind metav1.GroupVersionKind) *metav1.GroupVersionKind {
+	if requestKind != nil {
+		return requestKind
+	}
+
+	return &kind
+}
+
+// requestGVR returns the GVR of the original request, old apiservers (<1.15) don't
+// set `requestResource` on v1beta1 reviews, in that case we fallback to `resource`.
+func requestGVR(requestResource *metav1.GroupVersionResource, resource metav1.GroupVersionResource) *metav1.GroupVersionResource {
+	if requestResource != nil {
+		return requestResource
+	}
+
+	return &resource
+}
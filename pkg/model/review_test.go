@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	admissionv1 "k8s.io/api/admission/v1"
 	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	authenticationv1 "k8s.io/api/authentication/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 
@@ -34,6 +35,11 @@ func getBaseARV1Beta1() *admissionv1beta1.AdmissionReview {
 			OldObject:       runtime.RawExtension{Raw: []byte("old-raw-thingy")},
 			Object:          runtime.RawExtension{Raw: []byte("raw-thingy")},
 			DryRun:          &trueBool,
+			UserInfo: authenticationv1.UserInfo{
+				Username: "user1",
+				UID:      "uid1",
+				Groups:   []string{"group1", "group2"},
+			},
 		},
 	}
 }
@@ -55,6 +61,11 @@ func getBaseARV1() *admissionv1.AdmissionReview {
 			OldObject:       runtime.RawExtension{Raw: []byte("old-raw-thingy")},
 			Object:          runtime.RawExtension{Raw: []byte("raw-thingy")},
 			DryRun:          &trueBool,
+			UserInfo: authenticationv1.UserInfo{
+				Username: "user1",
+				UID:      "uid1",
+				Groups:   []string{"group1", "group2"},
+			},
 		},
 	}
 }
@@ -72,6 +83,11 @@ func getBaseModelV1Beta1() model.AdmissionReview {
 		RequestGVR:              &metav1.GroupVersionResource{Group: "core", Resource: "pods", Version: "v1"},
 		RequestGVK:              &metav1.GroupVersionKind{Group: "core", Kind: "Pod", Version: "v1"},
 		DryRun:                  true,
+		UserInfo: authenticationv1.UserInfo{
+			Username: "user1",
+			UID:      "uid1",
+			Groups:   []string{"group1", "group2"},
+		},
 	}
 }
 
@@ -88,6 +104,11 @@ func getBaseModelV1() model.AdmissionReview {
 		RequestGVR:              &metav1.GroupVersionResource{Group: "core", Resource: "pods", Version: "v1"},
 		RequestGVK:              &metav1.GroupVersionKind{Group: "core", Kind: "Pod", Version: "v1"},
 		DryRun:                  true,
+		UserInfo: authenticationv1.UserInfo{
+			Username: "user1",
+			UID:      "uid1",
+			Groups:   []string{"group1", "group2"},
+		},
 	}
 }
 
@@ -168,6 +189,43 @@ func TestNewAdmissionReviewV1Beta1(t *testing.T) {
 				return m
 			},
 		},
+
+		"Regular Kubernetes object to model (without request kind and resource, old apiservers).": {
+			ar: func() *admissionv1beta1.AdmissionReview {
+				o := getBaseARV1Beta1()
+				o.Request.RequestKind = nil
+				o.Request.RequestResource = nil
+				o.Request.Resource = metav1.GroupVersionResource{Group: "core", Resource: "pods", Version: "v1"}
+				return o
+			},
+			expModel: func() model.AdmissionReview {
+				o := getBaseARV1Beta1()
+				o.Request.RequestKind = nil
+				o.Request.RequestResource = nil
+				o.Request.Resource = metav1.GroupVersionResource{Group: "core", Resource: "pods", Version: "v1"}
+
+				m := getBaseModelV1Beta1()
+				m.OriginalAdmissionReview = o
+				return m
+			},
+		},
+
+		"Regular Kubernetes object to model (subresource).": {
+			ar: func() *admissionv1beta1.AdmissionReview {
+				o := getBaseARV1Beta1()
+				o.Request.SubResource = "status"
+				return o
+			},
+			expModel: func() model.AdmissionReview {
+				o := getBaseARV1Beta1()
+				o.Request.SubResource = "status"
+
+				m := getBaseModelV1Beta1()
+				m.OriginalAdmissionReview = o
+				m.SubResource = "status"
+				return m
+			},
+		},
 	}
 
 	for name, test := range tests {
@@ -255,6 +313,23 @@ func TestNewAdmissionReviewV1(t *testing.T) {
 				return m
 			},
 		},
+
+		"Regular Kubernetes object to model (subresource).": {
+			ar: func() *admissionv1.AdmissionReview {
+				o := getBaseARV1()
+				o.Request.SubResource = "status"
+				return o
+			},
+			expModel: func() model.AdmissionReview {
+				o := getBaseARV1()
+				o.Request.SubResource = "status"
+
+				m := getBaseModelV1()
+				m.OriginalAdmissionReview = o
+				m.SubResource = "status"
+				return m
+			},
+		},
 	}
 
 	for name, test := range tests {
@@ -0,0 +1,66 @@
+package opentracing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	otlog "github.com/opentracing/opentracing-go/log"
+
+	"github.com/slok/kubewebhook/v2/pkg/tracing"
+)
+
+type tracer struct {
+	tracer opentracing.Tracer
+}
+
+// NewTracer returns a new tracing.Tracer for an OpenTracing implementation.
+func NewTracer(t opentracing.Tracer) tracing.Tracer {
+	return tracer{tracer: t}
+}
+
+func (t tracer) NewTrace(ctx context.Context, name string) context.Context {
+	_, ctx = opentracing.StartSpanFromContextWithTracer(ctx, t.tracer, name)
+	return ctx
+}
+
+func (t tracer) NewHTTPTrace(r *http.Request, name string) context.Context {
+	// Continue the trace if we have one propagated on the request.
+	var opts []opentracing.StartSpanOption
+	spanCtx, err := t.tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(r.Header))
+	if err == nil {
+		opts = append(opts, ext.RPCServerOption(spanCtx))
+	}
+
+	span := t.tracer.StartSpan(name, opts...)
+	ext.HTTPMethod.Set(span, r.Method)
+	ext.HTTPUrl.Set(span, r.URL.String())
+
+	return opentracing.ContextWithSpan(r.Context(), span)
+}
+
+func (t tracer) SetValuesOnTrace(ctx context.Context, values map[string]interface{}) {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return
+	}
+
+	for k, v := range values {
+		span.SetTag(k, v)
+	}
+}
+
+func (t tracer) EndTrace(ctx context.Context, err error) {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return
+	}
+
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.LogFields(otlog.Error(err))
+	}
+
+	span.Finish()
+}
@@ -0,0 +1,104 @@
+package opentracing_test
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	kwhopentracing "github.com/slok/kubewebhook/v2/pkg/tracing/opentracing"
+)
+
+func TestTracer(t *testing.T) {
+	tests := map[string]struct {
+		trace    func(t *testing.T, mt *mocktracer.MockTracer)
+		expSpans func(t *testing.T, spans []*mocktracer.MockSpan)
+	}{
+		"A trace with values should be traced with its tags.": {
+			trace: func(t *testing.T, mt *mocktracer.MockTracer) {
+				tracer := kwhopentracing.NewTracer(mt)
+				ctx := tracer.NewTrace(context.TODO(), "test1")
+				tracer.SetValuesOnTrace(ctx, map[string]interface{}{"k1": "v1", "k2": 42})
+				tracer.EndTrace(ctx, nil)
+			},
+			expSpans: func(t *testing.T, spans []*mocktracer.MockSpan) {
+				require.Len(t, spans, 1)
+				assert.Equal(t, "test1", spans[0].OperationName)
+				assert.Equal(t, map[string]interface{}{"k1": "v1", "k2": 42}, spans[0].Tags())
+			},
+		},
+
+		"A trace ended with an error should be marked as an error.": {
+			trace: func(t *testing.T, mt *mocktracer.MockTracer) {
+				tracer := kwhopentracing.NewTracer(mt)
+				ctx := tracer.NewTrace(context.TODO(), "test1")
+				tracer.EndTrace(ctx, fmt.Errorf("wanted error"))
+			},
+			expSpans: func(t *testing.T, spans []*mocktracer.MockSpan) {
+				require.Len(t, spans, 1)
+				assert.Equal(t, true, spans[0].Tag("error"))
+				assert.Len(t, spans[0].Logs(), 1)
+			},
+		},
+
+		"A trace inside a trace should be a child of the parent trace.": {
+			trace: func(t *testing.T, mt *mocktracer.MockTracer) {
+				tracer := kwhopentracing.NewTracer(mt)
+				ctx := tracer.NewTrace(context.TODO(), "parent")
+				cctx := tracer.NewTrace(ctx, "child")
+				tracer.EndTrace(cctx, nil)
+				tracer.EndTrace(ctx, nil)
+			},
+			expSpans: func(t *testing.T, spans []*mocktracer.MockSpan) {
+				require.Len(t, spans, 2)
+				assert.Equal(t, "child", spans[0].OperationName)
+				assert.Equal(t, "parent", spans[1].OperationName)
+				assert.Equal(t, spans[1].SpanContext.SpanID, spans[0].ParentID)
+			},
+		},
+
+		"An HTTP trace should continue the trace propagated on the request.": {
+			trace: func(t *testing.T, mt *mocktracer.MockTracer) {
+				parent := mt.StartSpan("remote")
+				r := httptest.NewRequest("POST", "/wh", nil)
+				err := mt.Inject(parent.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(r.Header))
+				require.NoError(t, err)
+				parent.Finish()
+
+				tracer := kwhopentracing.NewTracer(mt)
+				ctx := tracer.NewHTTPTrace(r, "http")
+				tracer.EndTrace(ctx, nil)
+			},
+			expSpans: func(t *testing.T, spans []*mocktracer.MockSpan) {
+				require.Len(t, spans, 2)
+				assert.Equal(t, "http", spans[1].OperationName)
+				assert.Equal(t, spans[0].SpanContext.SpanID, spans[1].ParentID)
+				assert.Equal(t, spans[0].SpanContext.TraceID, spans[1].SpanContext.TraceID)
+			},
+		},
+
+		"Ending a context without trace should not fail.": {
+			trace: func(t *testing.T, mt *mocktracer.MockTracer) {
+				tracer := kwhopentracing.NewTracer(mt)
+				tracer.SetValuesOnTrace(context.TODO(), map[string]interface{}{"k1": "v1"})
+				tracer.EndTrace(context.TODO(), nil)
+			},
+			expSpans: func(t *testing.T, spans []*mocktracer.MockSpan) {
+				assert.Len(t, spans, 0)
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mt := mocktracer.New()
+			test.trace(t, mt)
+			test.expSpans(t, mt.FinishedSpans())
+		})
+	}
+}
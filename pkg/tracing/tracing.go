@@ -0,0 +1,31 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+)
+
+// Tracer knows how to trace the operations of the webhooks.
+type Tracer interface {
+	// NewTrace starts a new trace, if the context already has a trace, it will be
+	// the parent of the new one.
+	NewTrace(ctx context.Context, name string) context.Context
+	// NewHTTPTrace is like NewTrace but the parent of the trace will be the one propagated
+	// on the HTTP request (if any).
+	NewHTTPTrace(r *http.Request, name string) context.Context
+	// SetValuesOnTrace sets key values (tags, attributes...) on the context trace.
+	SetValuesOnTrace(ctx context.Context, values map[string]interface{})
+	// EndTrace ends the context trace, if the error is not nil the trace will be set
+	// as failed.
+	EndTrace(ctx context.Context, err error)
+}
+
+// Noop tracer doesn't trace anything.
+const Noop = noop(0)
+
+type noop int
+
+func (n noop) NewTrace(ctx context.Context, name string) context.Context           { return ctx }
+func (n noop) NewHTTPTrace(r *http.Request, name string) context.Context           { return r.Context() }
+func (n noop) SetValuesOnTrace(ctx context.Context, values map[string]interface{}) {}
+func (n noop) EndTrace(ctx context.Context, err error)                             {}
@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/slok/kubewebhook/v2/pkg/model"
+)
+
+type contextKey string
+
+const (
+	// contextIDKey used as unique key to store the webhook ID in the context.
+	contextIDKey = contextKey("kubewebhook-webhook-id")
+	// contextReviewKey used as unique key to store the admission review in the context.
+	contextReviewKey = contextKey("kubewebhook-webhook-review")
+)
+
+// IDFromContext returns the ID of the webhook that is reviewing the admission review, this
+// can be used by mutators and validators to identify the webhook (e.g logging) when multiple
+// webhooks share the same logic.
+//
+// If the context doesn't have a webhook ID it will return empty.
+func IDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextIDKey).(string)
+	return id
+}
+
+// ContextWithID returns a new context with the webhook ID. Normally this is used by the
+// webhook implementations to set their ID on the review context.
+func ContextWithID(parent context.Context, id string) context.Context {
+	return context.WithValue(parent, contextIDKey, id)
+}
+
+// ReviewFromContext returns the admission review that is being reviewed, this can be used by
+// the code called by mutators and validators that only receives the context (e.g policies) to
+// get any data of the request (operation, dry run, user info, subresource...). The original
+// Kubernetes admission review (`v1` or `v1beta1`) is available on `OriginalAdmissionReview`.
+//
+// If the context doesn't have an admission review it will return `nil`.
+func ReviewFromContext(ctx context.Context) *model.AdmissionReview {
+	ar, _ := ctx.Value(contextReviewKey).(*model.AdmissionReview)
+	return ar
+}
+
+// ContextWithReview returns a new context with the admission review. Normally this is used by
+// the webhook implementations to set the review on the review context.
+func ContextWithReview(parent context.Context, ar *model.AdmissionReview) context.Context {
+	return context.WithValue(parent, contextReviewKey, ar)
+}
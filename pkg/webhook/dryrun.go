@@ -0,0 +1,35 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/slok/kubewebhook/v2/pkg/model"
+)
+
+type skipDryRunWebhook struct {
+	webhookKind model.WebhookKind
+	next        Webhook
+}
+
+// NewSkipDryRunWebhook returns a wrapped webhook that will allow the dry-run reviews without
+// mutation or validation, the wrapped webhook will only review the non dry-run reviews.
+//
+// Useful on webhooks with side effects (e.g calling external APIs) that must not be executed
+// on dry-run requests (e.g `kubectl apply --dry-run=server`), as these will not be persisted
+// by the apiserver.
+func NewSkipDryRunWebhook(next Webhook) Webhook {
+	return skipDryRunWebhook{
+		webhookKind: next.Kind(),
+		next:        next,
+	}
+}
+
+func (s skipDryRunWebhook) ID() string              { return s.next.ID() }
+func (s skipDryRunWebhook) Kind() model.WebhookKind { return s.next.Kind() }
+func (s skipDryRunWebhook) Review(ctx context.Context, ar model.AdmissionReview) (model.AdmissionResponse, error) {
+	if ar.DryRun {
+		return allowedResponse(s.webhookKind, ar)
+	}
+
+	return s.next.Review(ctx, ar)
+}
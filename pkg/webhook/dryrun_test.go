@@ -0,0 +1,66 @@
+package webhook_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/webhook"
+	"github.com/slok/kubewebhook/v2/pkg/webhook/webhookmock"
+)
+
+func TestSkipDryRunWebhook(t *testing.T) {
+	tests := map[string]struct {
+		kind    model.WebhookKind
+		review  model.AdmissionReview
+		mock    func(mw *webhookmock.Webhook)
+		expResp model.AdmissionResponse
+	}{
+		"A regular review should be reviewed.": {
+			kind:   model.WebhookKindMutating,
+			review: model.AdmissionReview{ID: "test"},
+			mock: func(mw *webhookmock.Webhook) {
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(&model.MutatingAdmissionResponse{ID: "test", JSONPatchPatch: []byte("[]")}, nil)
+			},
+			expResp: &model.MutatingAdmissionResponse{ID: "test", JSONPatchPatch: []byte("[]")},
+		},
+
+		"A dry-run review on a mutating webhook should not be mutated.": {
+			kind:    model.WebhookKindMutating,
+			review:  model.AdmissionReview{ID: "test", DryRun: true},
+			mock:    func(mw *webhookmock.Webhook) {},
+			expResp: &model.MutatingAdmissionResponse{ID: "test"},
+		},
+
+		"A dry-run review on a validating webhook should be allowed.": {
+			kind:    model.WebhookKindValidating,
+			review:  model.AdmissionReview{ID: "test", DryRun: true},
+			mock:    func(mw *webhookmock.Webhook) {},
+			expResp: &model.ValidatingAdmissionResponse{ID: "test", Allowed: true},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mocks.
+			mw := &webhookmock.Webhook{}
+			mw.On("Kind").Once().Return(test.kind)
+			test.mock(mw)
+
+			// Execute.
+			wh := webhook.NewSkipDryRunWebhook(mw)
+			gotResp, err := wh.Review(context.TODO(), test.review)
+
+			// Check.
+			if assert.NoError(err) {
+				assert.Equal(test.expResp, gotResp)
+			}
+			mw.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package webhook
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// StatusError is an error that can be returned by the webhooks (mutators, validators...)
+// to customize the status returned on the admission review response. Any other error
+// type will be returned as an internal error (500).
+type StatusError struct {
+	// Code is the HTTP like status code (e.g 403).
+	Code int32
+	// Reason is the machine readable reason of the error (e.g `Forbidden`).
+	Reason metav1.StatusReason
+	// Message is the human readable message that will be shown to the user.
+	Message string
+}
+
+// NewStatusError returns a new StatusError.
+func NewStatusError(code int32, reason metav1.StatusReason, msg string) *StatusError {
+	return &StatusError{
+		Code:    code,
+		Reason:  reason,
+		Message: msg,
+	}
+}
+
+func (s *StatusError) Error() string { return s.Message }
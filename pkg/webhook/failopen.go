@@ -0,0 +1,105 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/slok/kubewebhook/v2/pkg/log"
+	"github.com/slok/kubewebhook/v2/pkg/model"
+)
+
+type failOpenWebhook struct {
+	webhookKind model.WebhookKind
+	allKinds    bool
+	kinds       []metav1.GroupVersionKind
+	logger      log.Logger
+	next        Webhook
+}
+
+// NewFailOpenWebhook returns a wrapped webhook that will allow the reviews (without mutation
+// or validation) when the wrapped webhook review fails, logging the error. By default the webhooks
+// fail closed, in other words, the review errors will not allow the resource.
+//
+// The intentional denials (`StatusError` with a client error code, e.g 403) are not failures and will
+// be returned as they are, only the unexpected errors, server error codes (5xx) and timeouts fail open.
+//
+// This is the same as using `failurePolicy: Ignore` on the webhook configuration, but handled
+// by the webhook app, useful for non critical webhooks that depend on external dependencies.
+func NewFailOpenWebhook(logger log.Logger, next Webhook) Webhook {
+	if logger == nil {
+		logger = log.Noop
+	}
+
+	return failOpenWebhook{
+		webhookKind: next.Kind(),
+		allKinds:    true,
+		logger:      logger.WithValues(log.Kv{"webhook-id": next.ID()}),
+		next:        next,
+	}
+}
+
+// NewKindFailOpenWebhook returns a wrapped webhook that will allow the reviews (without mutation
+// or validation) of the objects of the received kinds when the wrapped webhook review fails, logging
+// the error. The review errors of the rest of the kinds will not allow the resource (fail closed).
+//
+// Empty group and version on the kinds will match any group and version (e.g `{Kind: "Pod"}`
+// will match all the pods).
+//
+// Useful on webhooks that receive multiple types (e.g dynamic webhooks) and need a different
+// failure policy per kind, that can't be set on the webhook configuration `failurePolicy`.
+func NewKindFailOpenWebhook(logger log.Logger, kinds []metav1.GroupVersionKind, next Webhook) Webhook {
+	if logger == nil {
+		logger = log.Noop
+	}
+
+	return failOpenWebhook{
+		webhookKind: next.Kind(),
+		kinds:       kinds,
+		logger:      logger.WithValues(log.Kv{"webhook-id": next.ID()}),
+		next:        next,
+	}
+}
+
+func (f failOpenWebhook) ID() string              { return f.next.ID() }
+func (f failOpenWebhook) Kind() model.WebhookKind { return f.next.Kind() }
+func (f failOpenWebhook) Review(ctx context.Context, ar model.AdmissionReview) (model.AdmissionResponse, error) {
+	resp, err := f.next.Review(ctx, ar)
+	if err != nil && !isDenialError(err) && f.failOpen(ar) {
+		f.logger.WithCtxValues(ctx).Errorf("webhook review failed, allowing the resource: %s", err)
+		return allowedResponse(f.webhookKind, ar)
+	}
+
+	return resp, err
+}
+
+// failOpen checks if the admission review should be allowed on failure.
+func (f failOpenWebhook) failOpen(ar model.AdmissionReview) bool {
+	if f.allKinds {
+		return true
+	}
+
+	if ar.RequestGVK == nil {
+		return false
+	}
+
+	for _, kind := range f.kinds {
+		if kindMatches(kind, *ar.RequestGVK) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isDenialError checks if the error is an intentional denial of the resource (e.g a mutator returning
+// a forbidden `StatusError`), these are status errors with client error codes (4xx) and are not failures.
+func isDenialError(err error) bool {
+	var serr *StatusError
+	if !errors.As(err, &serr) {
+		return false
+	}
+
+	return serr.Code >= 400 && serr.Code < 500
+}
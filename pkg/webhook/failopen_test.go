@@ -0,0 +1,193 @@
+package webhook_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/slok/kubewebhook/v2/pkg/log"
+	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/webhook"
+	"github.com/slok/kubewebhook/v2/pkg/webhook/webhookmock"
+)
+
+func TestFailOpenWebhook(t *testing.T) {
+	tests := map[string]struct {
+		kind    model.WebhookKind
+		mock    func(mw *webhookmock.Webhook)
+		expResp model.AdmissionResponse
+		expErr  bool
+	}{
+		"A correct review should return the review response.": {
+			kind: model.WebhookKindValidating,
+			mock: func(mw *webhookmock.Webhook) {
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(&model.ValidatingAdmissionResponse{ID: "test", Allowed: false}, nil)
+			},
+			expResp: &model.ValidatingAdmissionResponse{ID: "test", Allowed: false},
+		},
+
+		"A failed review on a mutating webhook should not mutate.": {
+			kind: model.WebhookKindMutating,
+			mock: func(mw *webhookmock.Webhook) {
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(nil, fmt.Errorf("something"))
+			},
+			expResp: &model.MutatingAdmissionResponse{ID: "test"},
+		},
+
+		"A failed review on a validating webhook should allow.": {
+			kind: model.WebhookKindValidating,
+			mock: func(mw *webhookmock.Webhook) {
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(nil, fmt.Errorf("something"))
+			},
+			expResp: &model.ValidatingAdmissionResponse{ID: "test", Allowed: true},
+		},
+
+		"A review denied on purpose with a client error status should not allow.": {
+			kind: model.WebhookKindMutating,
+			mock: func(mw *webhookmock.Webhook) {
+				err := fmt.Errorf("could not mutate: %w", webhook.NewStatusError(403, metav1.StatusReasonForbidden, "not allowed by policy"))
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(nil, err)
+			},
+			expErr: true,
+		},
+
+		"A failed review with a server error status should allow.": {
+			kind: model.WebhookKindMutating,
+			mock: func(mw *webhookmock.Webhook) {
+				err := webhook.NewStatusError(503, metav1.StatusReasonServiceUnavailable, "something")
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(nil, err)
+			},
+			expResp: &model.MutatingAdmissionResponse{ID: "test"},
+		},
+
+		"A timed out review should allow.": {
+			kind: model.WebhookKindValidating,
+			mock: func(mw *webhookmock.Webhook) {
+				err := webhook.NewStatusError(504, metav1.StatusReasonTimeout, "timeout")
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(nil, err)
+			},
+			expResp: &model.ValidatingAdmissionResponse{ID: "test", Allowed: true},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mocks.
+			mw := &webhookmock.Webhook{}
+			mw.On("Kind").Once().Return(test.kind)
+			mw.On("ID").Once().Return("test-wh")
+			test.mock(mw)
+
+			// Execute.
+			wh := webhook.NewFailOpenWebhook(log.Noop, mw)
+			gotResp, err := wh.Review(context.TODO(), model.AdmissionReview{ID: "test"})
+
+			// Check.
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expResp, gotResp)
+			}
+			mw.AssertExpectations(t)
+		})
+	}
+}
+
+func TestKindFailOpenWebhook(t *testing.T) {
+	kinds := []metav1.GroupVersionKind{
+		{Kind: "Pod"},
+		{Group: "apps", Version: "v1", Kind: "Deployment"},
+	}
+
+	tests := map[string]struct {
+		kind    model.WebhookKind
+		review  model.AdmissionReview
+		mock    func(mw *webhookmock.Webhook)
+		expResp model.AdmissionResponse
+		expErr  bool
+	}{
+		"A correct review should return the review response.": {
+			kind:   model.WebhookKindValidating,
+			review: model.AdmissionReview{ID: "test", RequestGVK: &metav1.GroupVersionKind{Version: "v1", Kind: "Pod"}},
+			mock: func(mw *webhookmock.Webhook) {
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(&model.ValidatingAdmissionResponse{ID: "test", Allowed: false}, nil)
+			},
+			expResp: &model.ValidatingAdmissionResponse{ID: "test", Allowed: false},
+		},
+
+		"A failed review of a fail open kind on a mutating webhook should not mutate.": {
+			kind:   model.WebhookKindMutating,
+			review: model.AdmissionReview{ID: "test", RequestGVK: &metav1.GroupVersionKind{Version: "v1", Kind: "Pod"}},
+			mock: func(mw *webhookmock.Webhook) {
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(nil, fmt.Errorf("something"))
+			},
+			expResp: &model.MutatingAdmissionResponse{ID: "test"},
+		},
+
+		"A failed review of a fail open kind on a validating webhook should allow.": {
+			kind:   model.WebhookKindValidating,
+			review: model.AdmissionReview{ID: "test", RequestGVK: &metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}},
+			mock: func(mw *webhookmock.Webhook) {
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(nil, fmt.Errorf("something"))
+			},
+			expResp: &model.ValidatingAdmissionResponse{ID: "test", Allowed: true},
+		},
+
+		"A failed review of a not fail open kind should fail.": {
+			kind:   model.WebhookKindValidating,
+			review: model.AdmissionReview{ID: "test", RequestGVK: &metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "StatefulSet"}},
+			mock: func(mw *webhookmock.Webhook) {
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(nil, fmt.Errorf("something"))
+			},
+			expErr: true,
+		},
+
+		"A failed review of a not fail open group should fail.": {
+			kind:   model.WebhookKindValidating,
+			review: model.AdmissionReview{ID: "test", RequestGVK: &metav1.GroupVersionKind{Group: "extensions", Version: "v1beta1", Kind: "Deployment"}},
+			mock: func(mw *webhookmock.Webhook) {
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(nil, fmt.Errorf("something"))
+			},
+			expErr: true,
+		},
+
+		"A failed review without kind should fail.": {
+			kind:   model.WebhookKindValidating,
+			review: model.AdmissionReview{ID: "test"},
+			mock: func(mw *webhookmock.Webhook) {
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(nil, fmt.Errorf("something"))
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mocks.
+			mw := &webhookmock.Webhook{}
+			mw.On("Kind").Once().Return(test.kind)
+			mw.On("ID").Once().Return("test-wh")
+			test.mock(mw)
+
+			// Execute.
+			wh := webhook.NewKindFailOpenWebhook(log.Noop, kinds, mw)
+			gotResp, err := wh.Review(context.TODO(), test.review)
+
+			// Check.
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expResp, gotResp)
+			}
+			mw.AssertExpectations(t)
+		})
+	}
+}
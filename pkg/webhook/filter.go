@@ -0,0 +1,171 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/slok/kubewebhook/v2/pkg/log"
+	"github.com/slok/kubewebhook/v2/pkg/model"
+)
+
+type filteredWebhook struct {
+	webhookKind model.WebhookKind
+	selector    labels.Selector
+	auditOnly   bool
+	logger      log.Logger
+	next        Webhook
+}
+
+// NewFilteredWebhook returns a wrapped webhook that will only review the objects that
+// match the label selector, the ones that don't match will be allowed without
+// mutation or validation.
+//
+// Although the apiserver can filter the objects with the webhook `objectSelector`, this can
+// be used as defense in depth or to filter inside the app. Like the `objectSelector`, on updates
+// the object will be reviewed if the new or the old object match the selector.
+//
+// The objects that don't match the selector will be logged at debug level.
+func NewFilteredWebhook(logger log.Logger, selector labels.Selector, next Webhook) Webhook {
+	if logger == nil {
+		logger = log.Noop
+	}
+
+	return filteredWebhook{
+		webhookKind: next.Kind(),
+		selector:    selector,
+		logger:      logger.WithValues(log.Kv{"webhook-id": next.ID()}),
+		next:        next,
+	}
+}
+
+// NewSelectorAuditWebhook returns a wrapped webhook that will review all the objects, logging a warning
+// with the object labels when the object doesn't match the label selector.
+//
+// This can be used to detect misconfigurations between the webhook configuration `objectSelector`
+// and the webhook app (e.g the apiserver sending objects that should have been filtered), using the
+// same selector as the webhook configuration `objectSelector`.
+func NewSelectorAuditWebhook(logger log.Logger, selector labels.Selector, next Webhook) Webhook {
+	if logger == nil {
+		logger = log.Noop
+	}
+
+	return filteredWebhook{
+		webhookKind: next.Kind(),
+		selector:    selector,
+		auditOnly:   true,
+		logger:      logger.WithValues(log.Kv{"webhook-id": next.ID()}),
+		next:        next,
+	}
+}
+
+func (f filteredWebhook) ID() string              { return f.next.ID() }
+func (f filteredWebhook) Kind() model.WebhookKind { return f.next.Kind() }
+func (f filteredWebhook) Review(ctx context.Context, ar model.AdmissionReview) (model.AdmissionResponse, error) {
+	// Delete operations don't have body because should be gone on the deletion, instead they have the body
+	// of the object we want to delete as an old object.
+	raw := ar.NewObjectRaw
+	if ar.Operation == model.OperationDelete {
+		raw = ar.OldObjectRaw
+	}
+
+	match, objLabels, err := f.matches(raw)
+	if err == nil && !match && ar.Operation == model.OperationUpdate && len(ar.OldObjectRaw) > 0 {
+		// On updates the labels could have been changed, check the old object too.
+		match, _, err = f.matches(ar.OldObjectRaw)
+		if err != nil {
+			err = fmt.Errorf("old object: %w", err)
+		}
+	}
+
+	if err != nil {
+		// The audit can't change the review result.
+		if f.auditOnly {
+			f.logger.WithCtxValues(ctx).Errorf("could not check the object selector: %s", err)
+			return f.next.Review(ctx, ar)
+		}
+		return nil, err
+	}
+
+	if match {
+		return f.next.Review(ctx, ar)
+	}
+
+	if f.auditOnly {
+		f.logger.WithCtxValues(ctx).WithValues(log.Kv{"labels": objLabels.String(), "selector": f.selector.String()}).
+			Warningf("received object not matching configured selector")
+		return f.next.Review(ctx, ar)
+	}
+
+	f.logger.WithCtxValues(ctx).WithValues(log.Kv{"labels": objLabels.String(), "selector": f.selector.String()}).
+		Debugf("Object not matching the selector, skipping review")
+	return allowedResponse(f.webhookKind, ar)
+}
+
+func (f filteredWebhook) matches(raw []byte) (bool, labels.Set, error) {
+	obj := metav1.PartialObjectMetadata{}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return false, nil, fmt.Errorf("could not decode object metadata: %w", err)
+	}
+
+	objLabels := labels.Set(obj.Labels)
+	return f.selector.Matches(objLabels), objLabels, nil
+}
+
+// allowedResponse returns a response that allows the review without any mutation.
+func allowedResponse(kind model.WebhookKind, ar model.AdmissionReview) (model.AdmissionResponse, error) {
+	switch kind {
+	case model.WebhookKindMutating:
+		return &model.MutatingAdmissionResponse{ID: ar.ID}, nil
+	case model.WebhookKindValidating:
+		return &model.ValidatingAdmissionResponse{ID: ar.ID, Allowed: true}, nil
+	}
+
+	return nil, fmt.Errorf("unknown webhook kind: %s", kind)
+}
+
+type kindFilteredWebhook struct {
+	webhookKind model.WebhookKind
+	kinds       []metav1.GroupVersionKind
+	next        Webhook
+}
+
+// NewKindFilteredWebhook returns a wrapped webhook that will only review the objects of the
+// received kinds, the ones that don't match will be allowed without mutation or validation
+// and without decoding the object.
+//
+// Empty group and version on the kinds will match any group and version (e.g `{Kind: "Pod"}`
+// will match all the pods).
+//
+// Useful on webhooks that receive multiple types (e.g dynamic webhooks) and only want to
+// review some of them.
+func NewKindFilteredWebhook(kinds []metav1.GroupVersionKind, next Webhook) Webhook {
+	return kindFilteredWebhook{
+		webhookKind: next.Kind(),
+		kinds:       kinds,
+		next:        next,
+	}
+}
+
+func (k kindFilteredWebhook) ID() string              { return k.next.ID() }
+func (k kindFilteredWebhook) Kind() model.WebhookKind { return k.next.Kind() }
+func (k kindFilteredWebhook) Review(ctx context.Context, ar model.AdmissionReview) (model.AdmissionResponse, error) {
+	if ar.RequestGVK != nil {
+		for _, kind := range k.kinds {
+			if kindMatches(kind, *ar.RequestGVK) {
+				return k.next.Review(ctx, ar)
+			}
+		}
+	}
+
+	return allowedResponse(k.webhookKind, ar)
+}
+
+func kindMatches(expected, got metav1.GroupVersionKind) bool {
+	return expected.Kind == got.Kind &&
+		(expected.Group == "" || expected.Group == got.Group) &&
+		(expected.Version == "" || expected.Version == got.Version)
+}
@@ -0,0 +1,277 @@
+package webhook_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/slok/kubewebhook/v2/pkg/log"
+	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/webhook"
+	"github.com/slok/kubewebhook/v2/pkg/webhook/webhookmock"
+)
+
+func TestFilteredWebhook(t *testing.T) {
+	selector := labels.SelectorFromSet(labels.Set{"inject": "true"})
+	matchingRaw := []byte(`{"kind":"Pod","apiVersion":"v1","metadata":{"name":"test","labels":{"inject":"true"}}}`)
+	notMatchingRaw := []byte(`{"kind":"Pod","apiVersion":"v1","metadata":{"name":"test","labels":{"inject":"false"}}}`)
+
+	tests := map[string]struct {
+		kind    model.WebhookKind
+		review  model.AdmissionReview
+		mock    func(mw *webhookmock.Webhook)
+		expResp model.AdmissionResponse
+		expErr  bool
+	}{
+		"A matching object should be reviewed.": {
+			kind:   model.WebhookKindMutating,
+			review: model.AdmissionReview{ID: "test", Operation: model.OperationCreate, NewObjectRaw: matchingRaw},
+			mock: func(mw *webhookmock.Webhook) {
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(&model.MutatingAdmissionResponse{ID: "test", JSONPatchPatch: []byte("[]")}, nil)
+			},
+			expResp: &model.MutatingAdmissionResponse{ID: "test", JSONPatchPatch: []byte("[]")},
+		},
+
+		"A matching deleted object should be reviewed.": {
+			kind:   model.WebhookKindValidating,
+			review: model.AdmissionReview{ID: "test", Operation: model.OperationDelete, OldObjectRaw: matchingRaw},
+			mock: func(mw *webhookmock.Webhook) {
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(&model.ValidatingAdmissionResponse{ID: "test", Allowed: false}, nil)
+			},
+			expResp: &model.ValidatingAdmissionResponse{ID: "test", Allowed: false},
+		},
+
+		"A not matching object on a mutating webhook should not be mutated.": {
+			kind:    model.WebhookKindMutating,
+			review:  model.AdmissionReview{ID: "test", Operation: model.OperationCreate, NewObjectRaw: notMatchingRaw},
+			mock:    func(mw *webhookmock.Webhook) {},
+			expResp: &model.MutatingAdmissionResponse{ID: "test"},
+		},
+
+		"A not matching object on a validating webhook should be allowed.": {
+			kind:    model.WebhookKindValidating,
+			review:  model.AdmissionReview{ID: "test", Operation: model.OperationCreate, NewObjectRaw: notMatchingRaw},
+			mock:    func(mw *webhookmock.Webhook) {},
+			expResp: &model.ValidatingAdmissionResponse{ID: "test", Allowed: true},
+		},
+
+		"An updated object that matched the selector on the old object should be reviewed.": {
+			kind:   model.WebhookKindValidating,
+			review: model.AdmissionReview{ID: "test", Operation: model.OperationUpdate, NewObjectRaw: notMatchingRaw, OldObjectRaw: matchingRaw},
+			mock: func(mw *webhookmock.Webhook) {
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(&model.ValidatingAdmissionResponse{ID: "test", Allowed: false}, nil)
+			},
+			expResp: &model.ValidatingAdmissionResponse{ID: "test", Allowed: false},
+		},
+
+		"An updated object that didn't match the selector on the new and the old object should be allowed.": {
+			kind:    model.WebhookKindValidating,
+			review:  model.AdmissionReview{ID: "test", Operation: model.OperationUpdate, NewObjectRaw: notMatchingRaw, OldObjectRaw: notMatchingRaw},
+			mock:    func(mw *webhookmock.Webhook) {},
+			expResp: &model.ValidatingAdmissionResponse{ID: "test", Allowed: true},
+		},
+
+		"An invalid object should fail.": {
+			kind:   model.WebhookKindValidating,
+			review: model.AdmissionReview{ID: "test", Operation: model.OperationCreate, NewObjectRaw: []byte("{")},
+			mock:   func(mw *webhookmock.Webhook) {},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mocks.
+			mw := &webhookmock.Webhook{}
+			mw.On("Kind").Once().Return(test.kind)
+			mw.On("ID").Once().Return("test-wh")
+			test.mock(mw)
+
+			// Execute.
+			wh := webhook.NewFilteredWebhook(log.Noop, selector, mw)
+			gotResp, err := wh.Review(context.TODO(), test.review)
+
+			// Check.
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expResp, gotResp)
+			}
+			mw.AssertExpectations(t)
+		})
+	}
+}
+
+func TestFilteredWebhookLogging(t *testing.T) {
+	assert := assert.New(t)
+
+	selector := labels.SelectorFromSet(labels.Set{"inject": "true"})
+	mw := &webhookmock.Webhook{}
+	mw.On("Kind").Once().Return(model.WebhookKind(model.WebhookKindMutating))
+	mw.On("ID").Once().Return("test-wh")
+
+	lines := []string{}
+	wh := webhook.NewFilteredWebhook(levelRecorderLogger{Logger: log.Noop, lines: &lines}, selector, mw)
+	raw := []byte(`{"kind":"Pod","apiVersion":"v1","metadata":{"name":"test","labels":{"app":"test"}}}`)
+	_, err := wh.Review(context.TODO(), model.AdmissionReview{ID: "test", Operation: model.OperationCreate, NewObjectRaw: raw})
+
+	// Skipped objects should be logged at debug level.
+	if assert.NoError(err) {
+		expLines := []string{"debug Object not matching the selector, skipping review map[labels:app=test selector:inject=true webhook-id:test-wh]"}
+		assert.Equal(expLines, lines)
+	}
+	mw.AssertExpectations(t)
+}
+
+func TestSelectorAuditWebhook(t *testing.T) {
+	selector := labels.SelectorFromSet(labels.Set{"inject": "true"})
+	matchingRaw := []byte(`{"kind":"Pod","apiVersion":"v1","metadata":{"name":"test","labels":{"inject":"true"}}}`)
+	notMatchingRaw := []byte(`{"kind":"Pod","apiVersion":"v1","metadata":{"name":"test","labels":{"app":"test","inject":"false"}}}`)
+
+	tests := map[string]struct {
+		review   model.AdmissionReview
+		expLines []string
+	}{
+		"A matching object should be reviewed without logging.": {
+			review:   model.AdmissionReview{ID: "test", Operation: model.OperationCreate, NewObjectRaw: matchingRaw},
+			expLines: []string{},
+		},
+
+		"A not matching object should be reviewed logging a warning.": {
+			review: model.AdmissionReview{ID: "test", Operation: model.OperationCreate, NewObjectRaw: notMatchingRaw},
+			expLines: []string{
+				"warning received object not matching configured selector map[labels:app=test,inject=false selector:inject=true webhook-id:test-wh]",
+			},
+		},
+
+		"A not matching update with a matching old object should be reviewed without logging.": {
+			review:   model.AdmissionReview{ID: "test", Operation: model.OperationUpdate, NewObjectRaw: notMatchingRaw, OldObjectRaw: matchingRaw},
+			expLines: []string{},
+		},
+
+		"An object that can't be decoded should be reviewed logging an error.": {
+			review: model.AdmissionReview{ID: "test", Operation: model.OperationCreate, NewObjectRaw: []byte("{")},
+			expLines: []string{
+				"error could not check the object selector: could not decode object metadata: unexpected end of JSON input map[webhook-id:test-wh]",
+			},
+		},
+
+		"A deleted object without old object should be reviewed logging an error.": {
+			review: model.AdmissionReview{ID: "test", Operation: model.OperationDelete},
+			expLines: []string{
+				"error could not check the object selector: could not decode object metadata: unexpected end of JSON input map[webhook-id:test-wh]",
+			},
+		},
+
+		"An updated object with an old object that can't be decoded should be reviewed logging an error.": {
+			review: model.AdmissionReview{ID: "test", Operation: model.OperationUpdate, NewObjectRaw: notMatchingRaw, OldObjectRaw: []byte("{")},
+			expLines: []string{
+				"error could not check the object selector: old object: could not decode object metadata: unexpected end of JSON input map[webhook-id:test-wh]",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mocks.
+			mw := &webhookmock.Webhook{}
+			mw.On("Kind").Once().Return(model.WebhookKind(model.WebhookKindValidating))
+			mw.On("ID").Once().Return("test-wh")
+			expResp := &model.ValidatingAdmissionResponse{ID: "test", Allowed: false}
+			mw.On("Review", mock.Anything, mock.Anything).Once().Return(expResp, nil)
+
+			// Execute.
+			lines := []string{}
+			wh := webhook.NewSelectorAuditWebhook(levelRecorderLogger{Logger: log.Noop, lines: &lines}, selector, mw)
+			gotResp, err := wh.Review(context.TODO(), test.review)
+
+			// Check.
+			if assert.NoError(err) {
+				assert.Equal(expResp, gotResp)
+				assert.Equal(test.expLines, lines)
+			}
+			mw.AssertExpectations(t)
+		})
+	}
+}
+
+func TestKindFilteredWebhook(t *testing.T) {
+	kinds := []metav1.GroupVersionKind{
+		{Kind: "Pod"},
+		{Group: "apps", Version: "v1", Kind: "Deployment"},
+	}
+
+	tests := map[string]struct {
+		kind    model.WebhookKind
+		gvk     *metav1.GroupVersionKind
+		mock    func(mw *webhookmock.Webhook)
+		expResp model.AdmissionResponse
+	}{
+		"A matching kind should be reviewed.": {
+			kind: model.WebhookKindMutating,
+			gvk:  &metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+			mock: func(mw *webhookmock.Webhook) {
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(&model.MutatingAdmissionResponse{ID: "test", JSONPatchPatch: []byte("[]")}, nil)
+			},
+			expResp: &model.MutatingAdmissionResponse{ID: "test", JSONPatchPatch: []byte("[]")},
+		},
+
+		"A matching kind with group and version should be reviewed.": {
+			kind: model.WebhookKindValidating,
+			gvk:  &metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+			mock: func(mw *webhookmock.Webhook) {
+				mw.On("Review", mock.Anything, mock.Anything).Once().Return(&model.ValidatingAdmissionResponse{ID: "test", Allowed: false}, nil)
+			},
+			expResp: &model.ValidatingAdmissionResponse{ID: "test", Allowed: false},
+		},
+
+		"A not matching kind version should be allowed.": {
+			kind:    model.WebhookKindValidating,
+			gvk:     &metav1.GroupVersionKind{Group: "apps", Version: "v1beta1", Kind: "Deployment"},
+			mock:    func(mw *webhookmock.Webhook) {},
+			expResp: &model.ValidatingAdmissionResponse{ID: "test", Allowed: true},
+		},
+
+		"A not matching kind should not be mutated.": {
+			kind:    model.WebhookKindMutating,
+			gvk:     &metav1.GroupVersionKind{Version: "v1", Kind: "Service"},
+			mock:    func(mw *webhookmock.Webhook) {},
+			expResp: &model.MutatingAdmissionResponse{ID: "test"},
+		},
+
+		"A review without kind should not be mutated.": {
+			kind:    model.WebhookKindMutating,
+			mock:    func(mw *webhookmock.Webhook) {},
+			expResp: &model.MutatingAdmissionResponse{ID: "test"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mocks.
+			mw := &webhookmock.Webhook{}
+			mw.On("Kind").Once().Return(test.kind)
+			test.mock(mw)
+
+			// Execute.
+			wh := webhook.NewKindFilteredWebhook(kinds, mw)
+			gotResp, err := wh.Review(context.TODO(), model.AdmissionReview{ID: "test", RequestGVK: test.gvk})
+
+			// Check.
+			if assert.NoError(err) {
+				assert.Equal(test.expResp, gotResp)
+			}
+			mw.AssertExpectations(t)
+		})
+	}
+}
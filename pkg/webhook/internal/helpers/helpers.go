@@ -1,15 +1,21 @@
 package helpers
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"reflect"
 	"strings"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	clientsetscheme "k8s.io/client-go/kubernetes/scheme"
+
+	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/webhook"
 )
 
 // NewK8sObj returns a new object of a Kubernetes type based on the type.
@@ -29,12 +35,149 @@ func GetK8sObjType(obj metav1.Object) reflect.Type {
 	return reflect.Indirect(reflect.ValueOf(obj)).Type()
 }
 
+// ToK8sObj returns the Kubernetes object as a metav1.Object. Objects that can't be used as
+// metav1.Object (e.g `*runtime.Unknown` because the type is not registered on the scheme) will
+// return an error with the received type.
+func ToK8sObj(obj runtime.Object) (metav1.Object, error) {
+	mobj, ok := obj.(metav1.Object)
+	if !ok {
+		return nil, fmt.Errorf("got %T, cannot type assert as metav1.Object", obj)
+	}
+
+	return mobj, nil
+}
+
 // GroupVersionResourceToString returns a string representation. It differs from the
 // original stringer of the object itself.
 func GroupVersionResourceToString(gvr metav1.GroupVersionResource) string {
 	return strings.Join([]string{gvr.Group, "/", gvr.Version, "/", gvr.Resource}, "")
 }
 
+// ReviewObjectKind returns the kind of the object being reviewed, it will use the kind of the raw JSON object
+// and fallback to the kind of the admission review request if the raw object doesn't have it. If none of them
+// have the kind, it will return empty.
+func ReviewObjectKind(ar model.AdmissionReview, rawJSON []byte) string {
+	if kind := RawObjectKind(rawJSON); kind != "" {
+		return kind
+	}
+
+	if ar.RequestGVK != nil {
+		return ar.RequestGVK.Kind
+	}
+
+	return ""
+}
+
+// ReviewGVKs returns the GVKs of the admission review in priority order, first the GVK of the object being
+// reviewed (could be converted from the requested one, e.g `matchPolicy: Equivalent`) and then the requested
+// GVK. The missing GVKs will be ignored.
+func ReviewGVKs(ar model.AdmissionReview) []metav1.GroupVersionKind {
+	raw := ar.NewObjectRaw
+	if ar.Operation == model.OperationDelete {
+		raw = ar.OldObjectRaw
+	}
+
+	gvks := []metav1.GroupVersionKind{}
+	tm := metav1.TypeMeta{}
+	if err := json.Unmarshal(raw, &tm); err == nil && tm.Kind != "" {
+		if gv, err := schema.ParseGroupVersion(tm.APIVersion); err == nil {
+			gvks = append(gvks, metav1.GroupVersionKind{Group: gv.Group, Version: gv.Version, Kind: tm.Kind})
+		}
+	}
+
+	if ar.RequestGVK != nil && (len(gvks) == 0 || gvks[0] != *ar.RequestGVK) {
+		gvks = append(gvks, *ar.RequestGVK)
+	}
+
+	return gvks
+}
+
+// GVKMatches checks if the GVK matches the expected one, empty group and version on the expected
+// GVK will match any group and version.
+func GVKMatches(expected, got metav1.GroupVersionKind) bool {
+	return expected.Kind == got.Kind &&
+		(expected.Group == "" || expected.Group == got.Group) &&
+		(expected.Version == "" || expected.Version == got.Version)
+}
+
+// IsKindOfObject checks if the kind is of the object type. Empty kinds and unstructured objects (can be
+// of any kind) will be considered of the object kind.
+func IsKindOfObject(kind string, obj metav1.Object) bool {
+	if kind == "" {
+		return true
+	}
+
+	if _, ok := obj.(*unstructured.Unstructured); ok {
+		return true
+	}
+
+	return kind == objectKind(obj)
+}
+
+// objectKind returns the kind of the object type, it will use the kind registered on the Kubernetes client
+// scheme and fallback to the object type name for the types not registered (Kubernetes types are named
+// as their kind).
+func objectKind(obj metav1.Object) string {
+	if robj, ok := obj.(runtime.Object); ok {
+		if gvks, _, err := clientsetscheme.Scheme.ObjectKinds(robj); err == nil && len(gvks) > 0 {
+			return gvks[0].Kind
+		}
+	}
+
+	return GetK8sObjType(obj).Name()
+}
+
+// RawObjectKind returns the kind of the raw JSON object, if the kind is missing it will return empty.
+func RawObjectKind(rawJSON []byte) string {
+	tm := metav1.TypeMeta{}
+	if err := json.Unmarshal(rawJSON, &tm); err != nil {
+		return ""
+	}
+
+	return tm.Kind
+}
+
+// NewUnexpectedKindError returns a bad request error for a kind that is not of the expected object kind
+// (e.g a static webhook receiving other types due to a webhook misconfiguration).
+func NewUnexpectedKindError(kind string, obj metav1.Object) error {
+	msg := fmt.Sprintf("unexpected object kind, expected %q, got %q", objectKind(obj), kind)
+	return webhook.NewStatusError(http.StatusBadRequest, metav1.StatusReasonBadRequest, msg)
+}
+
+// ReviewTraceValues returns the values that identify an admission review on a trace.
+func ReviewTraceValues(webhookID string, ar model.AdmissionReview) map[string]interface{} {
+	kind := ""
+	if gvk := ar.RequestGVK; gvk != nil {
+		kind = strings.Trim(strings.Join([]string{gvk.Group, gvk.Version, gvk.Kind}, "/"), "/")
+	}
+
+	return map[string]interface{}{
+		"webhook-id": webhookID,
+		"request-id": ar.ID,
+		"op":         string(ar.Operation),
+		"kind":       kind,
+		"ns":         ar.Namespace,
+		"name":       ar.Name,
+		"dry-run":    ar.DryRun,
+	}
+}
+
+// MergeAuditAnnotations merges the audit annotations, on the same keys the new ones have priority.
+func MergeAuditAnnotations(current, new map[string]string) map[string]string {
+	if len(new) == 0 {
+		return current
+	}
+
+	if current == nil {
+		current = map[string]string{}
+	}
+	for k, v := range new {
+		current[k] = v
+	}
+
+	return current
+}
+
 // ObjectCreator knows how to create objects from Raw JSON data into specific types.
 type ObjectCreator interface {
 	NewObject(rawJSON []byte) (runtime.Object, error)
@@ -47,11 +190,16 @@ type staticObjectCreator struct {
 
 // NewStaticObjectCreator doesn't need to infer the type, it will create a new schema and create a new
 // object with the same type from the received object type.
-func NewStaticObjectCreator(obj metav1.Object) ObjectCreator {
-	codecs := serializer.NewCodecFactory(runtime.NewScheme())
+//
+// If the decoder is `nil` it will use the universal deserializer.
+func NewStaticObjectCreator(obj metav1.Object, decoder runtime.Decoder) ObjectCreator {
+	if decoder == nil {
+		decoder = serializer.NewCodecFactory(runtime.NewScheme()).UniversalDeserializer()
+	}
+
 	return staticObjectCreator{
 		objType:      GetK8sObjType(obj),
-		deserializer: codecs.UniversalDeserializer(),
+		deserializer: decoder,
 	}
 }
 
@@ -77,15 +225,26 @@ type dynamicObjectCreator struct {
 // NewDynamicObjectCreator returns a object creator that knows how to return objects from raw
 // JSON data without the need of knowing the type.
 //
-// To be able to infer the types the types need to be registered on the global client Scheme.
-// Normally when a user tries casting the metav1.Object to a specific type, the object is already
+// To be able to infer the types the types need to be registered on the received scheme, if
+// the scheme is `nil` it will use the global client Scheme that has all the Kubernetes core types
 // registered. In case the type is not registered and the object can't be created it will fallback
 // to an Unstructured type.
 //
+// If the decoder is not `nil` it will be used instead of the scheme universal deserializer, the
+// Unstructured fallback will be used too in case the decoder can't decode the object.
+//
 // Useful to make dynamic webhooks that expect multiple or unknown types.
-func NewDynamicObjectCreator() ObjectCreator {
+func NewDynamicObjectCreator(scheme *runtime.Scheme, decoder runtime.Decoder) ObjectCreator {
+	if decoder == nil {
+		codecs := clientsetscheme.Codecs
+		if scheme != nil {
+			codecs = serializer.NewCodecFactory(scheme)
+		}
+		decoder = codecs.UniversalDeserializer()
+	}
+
 	return dynamicObjectCreator{
-		universalDecoder:    clientsetscheme.Codecs.UniversalDeserializer(),
+		universalDecoder:    decoder,
 		unstructuredDecoder: unstructured.UnstructuredJSONScheme,
 	}
 }
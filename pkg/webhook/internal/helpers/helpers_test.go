@@ -0,0 +1,178 @@
+package helpers_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/webhook/internal/helpers"
+)
+
+func TestToK8sObj(t *testing.T) {
+	tests := map[string]struct {
+		obj    runtime.Object
+		expObj metav1.Object
+		expErr string
+	}{
+		"A typed object should be returned as a Kubernetes object.": {
+			obj:    &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test"}},
+			expObj: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test"}},
+		},
+
+		"An unstructured object should be returned as a Kubernetes object.": {
+			obj:    &unstructured.Unstructured{Object: map[string]interface{}{"kind": "Foo"}},
+			expObj: &unstructured.Unstructured{Object: map[string]interface{}{"kind": "Foo"}},
+		},
+
+		"An unknown object should fail with the type of the received object.": {
+			obj:    &runtime.Unknown{},
+			expErr: "got *runtime.Unknown, cannot type assert as metav1.Object",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			gotObj, err := helpers.ToK8sObj(test.obj)
+
+			if test.expErr != "" {
+				assert.EqualError(err, test.expErr)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expObj, gotObj)
+			}
+		})
+	}
+}
+
+func TestMergeAuditAnnotations(t *testing.T) {
+	tests := map[string]struct {
+		current map[string]string
+		new     map[string]string
+		exp     map[string]string
+	}{
+		"Without new annotations should return the current ones.": {
+			current: map[string]string{"k1": "v1"},
+			exp:     map[string]string{"k1": "v1"},
+		},
+
+		"Without current annotations should return the new ones.": {
+			new: map[string]string{"k1": "v1"},
+			exp: map[string]string{"k1": "v1"},
+		},
+
+		"On the same keys the new annotations should have priority.": {
+			current: map[string]string{"k1": "v1", "k2": "v2"},
+			new:     map[string]string{"k2": "v22", "k3": "v3"},
+			exp:     map[string]string{"k1": "v1", "k2": "v22", "k3": "v3"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			got := helpers.MergeAuditAnnotations(test.current, test.new)
+			assert.Equal(test.exp, got)
+		})
+	}
+}
+
+func TestIsKindOfObject(t *testing.T) {
+	tests := map[string]struct {
+		kind string
+		obj  metav1.Object
+		exp  bool
+	}{
+		"An empty kind should be of the object kind.": {
+			kind: "",
+			obj:  &corev1.Pod{},
+			exp:  true,
+		},
+
+		"The kind of the object should be of the object kind.": {
+			kind: "Pod",
+			obj:  &corev1.Pod{},
+			exp:  true,
+		},
+
+		"Other kind should not be of the object kind.": {
+			kind: "Service",
+			obj:  &corev1.Pod{},
+			exp:  false,
+		},
+
+		"Any kind should be of an unstructured object kind.": {
+			kind: "Foo",
+			obj:  &unstructured.Unstructured{},
+			exp:  true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			got := helpers.IsKindOfObject(test.kind, test.obj)
+			assert.Equal(test.exp, got)
+		})
+	}
+}
+
+func TestReviewGVKs(t *testing.T) {
+	tests := map[string]struct {
+		review  model.AdmissionReview
+		expGVKs []metav1.GroupVersionKind
+	}{
+		"A review without kinds should not return GVKs.": {
+			review:  model.AdmissionReview{},
+			expGVKs: []metav1.GroupVersionKind{},
+		},
+
+		"A review with only the requested kind should return the requested GVK.": {
+			review:  model.AdmissionReview{RequestGVK: &metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}},
+			expGVKs: []metav1.GroupVersionKind{{Group: "apps", Version: "v1", Kind: "Deployment"}},
+		},
+
+		"A review with the same object and requested kind should return a single GVK.": {
+			review: model.AdmissionReview{
+				RequestGVK:   &metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+				NewObjectRaw: []byte(`{"kind":"Deployment","apiVersion":"apps/v1"}`),
+			},
+			expGVKs: []metav1.GroupVersionKind{{Group: "apps", Version: "v1", Kind: "Deployment"}},
+		},
+
+		"A review with an object converted from the requested kind should return the object GVK first.": {
+			review: model.AdmissionReview{
+				RequestGVK:   &metav1.GroupVersionKind{Group: "apps", Version: "v1beta1", Kind: "Deployment"},
+				NewObjectRaw: []byte(`{"kind":"Deployment","apiVersion":"apps/v1"}`),
+			},
+			expGVKs: []metav1.GroupVersionKind{
+				{Group: "apps", Version: "v1", Kind: "Deployment"},
+				{Group: "apps", Version: "v1beta1", Kind: "Deployment"},
+			},
+		},
+
+		"A delete review should use the old object GVK.": {
+			review: model.AdmissionReview{
+				Operation:    model.OperationDelete,
+				OldObjectRaw: []byte(`{"kind":"Pod","apiVersion":"v1"}`),
+			},
+			expGVKs: []metav1.GroupVersionKind{{Version: "v1", Kind: "Pod"}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			gotGVKs := helpers.ReviewGVKs(test.review)
+			assert.Equal(test.expGVKs, gotGVKs)
+		})
+	}
+}
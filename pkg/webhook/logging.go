@@ -0,0 +1,51 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"github.com/slok/kubewebhook/v2/pkg/log"
+	"github.com/slok/kubewebhook/v2/pkg/model"
+)
+
+type loggedWebhook struct {
+	logger log.Logger
+	next   Webhook
+}
+
+// NewLoggedWebhook returns a wrapped webhook that will log the webhook reviews. The failed
+// reviews will be logged as errors and the rest in debug level.
+func NewLoggedWebhook(logger log.Logger, next Webhook) Webhook {
+	if logger == nil {
+		logger = log.Noop
+	}
+
+	return loggedWebhook{
+		logger: logger.WithValues(log.Kv{"webhook-id": next.ID(), "webhook-kind": next.Kind()}),
+		next:   next,
+	}
+}
+
+func (l loggedWebhook) ID() string              { return l.next.ID() }
+func (l loggedWebhook) Kind() model.WebhookKind { return l.next.Kind() }
+func (l loggedWebhook) Review(ctx context.Context, ar model.AdmissionReview) (resp model.AdmissionResponse, err error) {
+	defer func(t0 time.Time) {
+		logger := l.logger.WithCtxValues(ctx).WithValues(log.Kv{
+			"request-id": ar.ID,
+			"op":         ar.Operation,
+			"kind":       reviewResourceKind(ar),
+			"ns":         ar.Namespace,
+			"name":       ar.Name,
+			"dry-run":    ar.DryRun,
+			"duration":   time.Since(t0),
+		})
+
+		if err != nil {
+			logger.Errorf("webhook review failed: %s", err)
+			return
+		}
+		logger.Debugf("webhook review finished")
+	}(time.Now())
+
+	return l.next.Review(ctx, ar)
+}
@@ -0,0 +1,114 @@
+package webhook_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/slok/kubewebhook/v2/pkg/log"
+	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/webhook"
+	"github.com/slok/kubewebhook/v2/pkg/webhook/webhookmock"
+)
+
+// levelRecorderLogger is a logger that records the messages with their level and values.
+type levelRecorderLogger struct {
+	log.Logger
+	values log.Kv
+	lines  *[]string
+}
+
+func (l levelRecorderLogger) Debugf(format string, args ...interface{}) {
+	l.record("debug", format, args...)
+}
+
+func (l levelRecorderLogger) Infof(format string, args ...interface{}) {
+	l.record("info", format, args...)
+}
+
+func (l levelRecorderLogger) Warningf(format string, args ...interface{}) {
+	l.record("warning", format, args...)
+}
+
+func (l levelRecorderLogger) Errorf(format string, args ...interface{}) {
+	l.record("error", format, args...)
+}
+
+func (l levelRecorderLogger) record(level, format string, args ...interface{}) {
+	values := log.Kv{}
+	for k, v := range l.values {
+		values[k] = v
+	}
+	delete(values, "duration")
+	*l.lines = append(*l.lines, fmt.Sprintf("%s %s %v", level, fmt.Sprintf(format, args...), values))
+}
+
+func (l levelRecorderLogger) WithValues(kv log.Kv) log.Logger {
+	values := log.Kv{}
+	for k, v := range l.values {
+		values[k] = v
+	}
+	for k, v := range kv {
+		values[k] = v
+	}
+	return levelRecorderLogger{Logger: l.Logger, values: values, lines: l.lines}
+}
+
+func (l levelRecorderLogger) WithCtxValues(ctx context.Context) log.Logger { return l }
+
+func TestLoggedWebhook(t *testing.T) {
+	review := model.AdmissionReview{
+		ID:         "test",
+		Name:       "test-name",
+		Namespace:  "test-ns",
+		Operation:  model.OperationCreate,
+		RequestGVK: &metav1.GroupVersionKind{Group: "", Version: "v1", Kind: "Pod"},
+	}
+
+	tests := map[string]struct {
+		resp     model.AdmissionResponse
+		err      error
+		expLines []string
+	}{
+		"A successful review should be logged in debug level.": {
+			resp: &model.ValidatingAdmissionResponse{ID: "test", Allowed: true},
+			expLines: []string{
+				"debug webhook review finished map[dry-run:false kind:v1/Pod name:test-name ns:test-ns op:create request-id:test webhook-id:test-wh webhook-kind:validating]",
+			},
+		},
+
+		"A failed review should be logged as an error.": {
+			err: fmt.Errorf("something"),
+			expLines: []string{
+				"error webhook review failed: something map[dry-run:false kind:v1/Pod name:test-name ns:test-ns op:create request-id:test webhook-id:test-wh webhook-kind:validating]",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mocks.
+			mwh := &webhookmock.Webhook{}
+			mwh.On("ID").Return("test-wh")
+			mwh.On("Kind").Return(model.WebhookKind(model.WebhookKindValidating))
+			mwh.On("Review", mock.Anything, review).Once().Return(test.resp, test.err)
+
+			// Execute.
+			lines := []string{}
+			logger := levelRecorderLogger{Logger: log.Noop, lines: &lines}
+			wh := webhook.NewLoggedWebhook(logger, mwh)
+			gotResp, err := wh.Review(context.TODO(), review)
+
+			// Check.
+			assert.Equal(test.err, err)
+			assert.Equal(test.resp, gotResp)
+			assert.Equal(test.expLines, lines)
+		})
+	}
+}
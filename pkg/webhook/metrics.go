@@ -21,6 +21,9 @@ type MeasureOpCommonData struct {
 	ResourceKind           string
 	DryRun                 bool
 	WarningsNumber         int
+	// Timeout is the time the review had to complete, obtained from the deadline of the review
+	// context (e.g: the HTTP handler `Timeout`). It will be 0 if the review doesn't have a deadline.
+	Timeout time.Duration
 }
 
 // MeasureValidatingOpData is the data to measure webhook validating operation data.
@@ -74,7 +77,11 @@ func (m measuredWebhook) ID() string              { return m.next.ID() }
 func (m measuredWebhook) Kind() model.WebhookKind { return m.next.Kind() }
 func (m measuredWebhook) Review(ctx context.Context, ar model.AdmissionReview) (resp model.AdmissionResponse, err error) {
 	defer func(t0 time.Time) {
-		gvk := ar.RequestGVK
+		var timeout time.Duration
+		if deadline, ok := ctx.Deadline(); ok {
+			timeout = deadline.Sub(t0)
+		}
+
 		cData := MeasureOpCommonData{
 			WebhookID:              m.webhookID,
 			AdmissionReviewVersion: string(ar.Version),
@@ -83,25 +90,35 @@ func (m measuredWebhook) Review(ctx context.Context, ar model.AdmissionReview) (
 			ResourceName:           ar.Name,
 			ResourceNamespace:      ar.Namespace,
 			Operation:              string(ar.Operation),
-			ResourceKind:           strings.Trim(strings.Join([]string{gvk.Group, gvk.Version, gvk.Kind}, "/"), "/"),
+			ResourceKind:           reviewResourceKind(ar),
 			DryRun:                 ar.DryRun,
+			Timeout:                timeout,
 		}
 
-		switch r := resp.(type) {
-		case *model.ValidatingAdmissionResponse:
-			cData.WebhookType = model.WebhookKindMutating
-			cData.WarningsNumber = len(r.Warnings)
+		// Use the webhook kind instead of the response type, on errors we will not have a response.
+		switch m.webhookKind {
+		case model.WebhookKindValidating:
+			cData.WebhookType = model.WebhookKindValidating
+			allowed := false
+			if r, ok := resp.(*model.ValidatingAdmissionResponse); ok && r != nil {
+				cData.WarningsNumber = len(r.Warnings)
+				allowed = r.Allowed
+			}
 			m.rec.MeasureValidatingWebhookReviewOp(ctx, MeasureValidatingOpData{
 				MeasureOpCommonData: cData,
-				Allowed:             r.Allowed,
+				Allowed:             allowed,
 			})
 
-		case *model.MutatingAdmissionResponse:
-			cData.WebhookType = model.WebhookKindValidating
-			cData.WarningsNumber = len(r.Warnings)
+		case model.WebhookKindMutating:
+			cData.WebhookType = model.WebhookKindMutating
+			mutated := false
+			if r, ok := resp.(*model.MutatingAdmissionResponse); ok && r != nil {
+				cData.WarningsNumber = len(r.Warnings)
+				mutated = len(r.JSONPatchPatch) > 0 && string(r.JSONPatchPatch) != "[]"
+			}
 			m.rec.MeasureMutatingWebhookReviewOp(ctx, MeasureMutatingOpData{
 				MeasureOpCommonData: cData,
-				Mutated:             len(r.JSONPatchPatch) > 0 && string(r.JSONPatchPatch) != "[]",
+				Mutated:             mutated,
 			})
 
 		default:
@@ -113,3 +130,13 @@ func (m measuredWebhook) Review(ctx context.Context, ar model.AdmissionReview) (
 
 	return m.next.Review(ctx, ar)
 }
+
+// reviewResourceKind returns the kind of the reviewed resource in `group/version/kind` format.
+func reviewResourceKind(ar model.AdmissionReview) string {
+	gvk := ar.RequestGVK
+	if gvk == nil {
+		return ""
+	}
+
+	return strings.Trim(strings.Join([]string{gvk.Group, gvk.Version, gvk.Kind}, "/"), "/")
+}
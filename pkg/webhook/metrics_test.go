@@ -0,0 +1,168 @@
+package webhook_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/webhook"
+	"github.com/slok/kubewebhook/v2/pkg/webhook/webhookmock"
+)
+
+type fakeRecorder struct {
+	validatingData []webhook.MeasureValidatingOpData
+	mutatingData   []webhook.MeasureMutatingOpData
+}
+
+func (f *fakeRecorder) MeasureValidatingWebhookReviewOp(_ context.Context, data webhook.MeasureValidatingOpData) {
+	data.Duration = 0
+	data.Timeout = data.Timeout.Round(time.Minute)
+	f.validatingData = append(f.validatingData, data)
+}
+
+func (f *fakeRecorder) MeasureMutatingWebhookReviewOp(_ context.Context, data webhook.MeasureMutatingOpData) {
+	data.Duration = 0
+	data.Timeout = data.Timeout.Round(time.Minute)
+	f.mutatingData = append(f.mutatingData, data)
+}
+
+func TestMeasuredWebhook(t *testing.T) {
+	review := model.AdmissionReview{
+		ID:         "test",
+		Name:       "test-name",
+		Namespace:  "test-ns",
+		Operation:  model.OperationCreate,
+		Version:    model.AdmissionReviewVersionV1,
+		RequestGVK: &metav1.GroupVersionKind{Group: "", Version: "v1", Kind: "Pod"},
+	}
+
+	commonData := webhook.MeasureOpCommonData{
+		WebhookID:              "test-wh",
+		AdmissionReviewVersion: "v1",
+		ResourceName:           "test-name",
+		ResourceNamespace:      "test-ns",
+		Operation:              "create",
+		ResourceKind:           "v1/Pod",
+	}
+
+	tests := map[string]struct {
+		timeout           time.Duration
+		kind              model.WebhookKind
+		resp              model.AdmissionResponse
+		err               error
+		expValidatingData []webhook.MeasureValidatingOpData
+		expMutatingData   []webhook.MeasureMutatingOpData
+	}{
+		"A validating webhook review should be measured.": {
+			kind: model.WebhookKindValidating,
+			resp: &model.ValidatingAdmissionResponse{Allowed: true, Warnings: []string{"w1", "w2"}},
+			expValidatingData: []webhook.MeasureValidatingOpData{
+				{
+					MeasureOpCommonData: func() webhook.MeasureOpCommonData {
+						d := commonData
+						d.WebhookType = model.WebhookKindValidating
+						d.Success = true
+						d.WarningsNumber = 2
+						return d
+					}(),
+					Allowed: true,
+				},
+			},
+		},
+
+		"A validating webhook review with error should be measured.": {
+			kind: model.WebhookKindValidating,
+			err:  fmt.Errorf("wanted error"),
+			expValidatingData: []webhook.MeasureValidatingOpData{
+				{
+					MeasureOpCommonData: func() webhook.MeasureOpCommonData {
+						d := commonData
+						d.WebhookType = model.WebhookKindValidating
+						return d
+					}(),
+				},
+			},
+		},
+
+		"A mutating webhook review should be measured.": {
+			kind: model.WebhookKindMutating,
+			resp: &model.MutatingAdmissionResponse{JSONPatchPatch: []byte(`[{"op":"remove","path":"/a"}]`)},
+			expMutatingData: []webhook.MeasureMutatingOpData{
+				{
+					MeasureOpCommonData: func() webhook.MeasureOpCommonData {
+						d := commonData
+						d.WebhookType = model.WebhookKindMutating
+						d.Success = true
+						return d
+					}(),
+					Mutated: true,
+				},
+			},
+		},
+
+		"A review with a deadline on the context should measure the review timeout.": {
+			timeout: time.Hour,
+			kind:    model.WebhookKindValidating,
+			resp:    &model.ValidatingAdmissionResponse{Allowed: true},
+			expValidatingData: []webhook.MeasureValidatingOpData{
+				{
+					MeasureOpCommonData: func() webhook.MeasureOpCommonData {
+						d := commonData
+						d.WebhookType = model.WebhookKindValidating
+						d.Success = true
+						d.Timeout = time.Hour
+						return d
+					}(),
+					Allowed: true,
+				},
+			},
+		},
+
+		"A mutating webhook review with error should be measured.": {
+			kind: model.WebhookKindMutating,
+			err:  fmt.Errorf("wanted error"),
+			expMutatingData: []webhook.MeasureMutatingOpData{
+				{
+					MeasureOpCommonData: func() webhook.MeasureOpCommonData {
+						d := commonData
+						d.WebhookType = model.WebhookKindMutating
+						return d
+					}(),
+				},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mocks.
+			mwh := &webhookmock.Webhook{}
+			mwh.On("ID").Return("test-wh")
+			mwh.On("Kind").Return(test.kind)
+			mwh.On("Review", mock.Anything, review).Once().Return(test.resp, test.err)
+
+			// Execute.
+			ctx := context.TODO()
+			if test.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, test.timeout)
+				defer cancel()
+			}
+			rec := &fakeRecorder{}
+			wh := webhook.NewMeasuredWebhook(rec, mwh)
+			_, _ = wh.Review(ctx, review)
+
+			// Check.
+			assert.Equal(test.expValidatingData, rec.validatingData)
+			assert.Equal(test.expMutatingData, rec.mutatingData)
+		})
+	}
+}
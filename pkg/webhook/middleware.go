@@ -0,0 +1,37 @@
+package webhook
+
+import (
+	"github.com/slok/kubewebhook/v2/pkg/log"
+	"github.com/slok/kubewebhook/v2/pkg/tracing"
+)
+
+// Middleware wraps a webhook with a cross-cutting concern (logging, metrics, tracing...).
+type Middleware func(next Webhook) Webhook
+
+// Chain wraps the webhook with the middlewares. The first middleware will be the outermost one,
+// in other words, the first one that will receive the admission review.
+//
+// e.g: `Chain(wh, LoggingMiddleware(logger), MetricsMiddleware(rec))` is the same as
+// `NewLoggedWebhook(logger, NewMeasuredWebhook(rec, wh))`.
+func Chain(w Webhook, mws ...Middleware) Webhook {
+	for i := len(mws) - 1; i >= 0; i-- {
+		w = mws[i](w)
+	}
+
+	return w
+}
+
+// LoggingMiddleware returns a middleware that logs the webhook reviews, check `NewLoggedWebhook`.
+func LoggingMiddleware(logger log.Logger) Middleware {
+	return func(next Webhook) Webhook { return NewLoggedWebhook(logger, next) }
+}
+
+// MetricsMiddleware returns a middleware that measures the webhook reviews, check `NewMeasuredWebhook`.
+func MetricsMiddleware(rec MetricsRecorder) Middleware {
+	return func(next Webhook) Webhook { return NewMeasuredWebhook(rec, next) }
+}
+
+// TracingMiddleware returns a middleware that traces the webhook reviews, check `NewTracedWebhook`.
+func TracingMiddleware(tracer tracing.Tracer) Middleware {
+	return func(next Webhook) Webhook { return NewTracedWebhook(tracer, next) }
+}
@@ -0,0 +1,81 @@
+package webhook_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/webhook"
+	"github.com/slok/kubewebhook/v2/pkg/webhook/webhookmock"
+)
+
+// orderWebhook records the order in which the wrapped webhooks are called.
+type orderWebhook struct {
+	webhook.Webhook
+	name  string
+	calls *[]string
+}
+
+func (o orderWebhook) Review(ctx context.Context, ar model.AdmissionReview) (model.AdmissionResponse, error) {
+	*o.calls = append(*o.calls, o.name)
+	return o.Webhook.Review(ctx, ar)
+}
+
+func orderMiddleware(name string, calls *[]string) webhook.Middleware {
+	return func(next webhook.Webhook) webhook.Webhook {
+		return orderWebhook{Webhook: next, name: name, calls: calls}
+	}
+}
+
+func TestChain(t *testing.T) {
+	tests := map[string]struct {
+		middlewares func(calls *[]string) []webhook.Middleware
+		expCalls    []string
+	}{
+		"Without middlewares the webhook should be called directly.": {
+			middlewares: func(calls *[]string) []webhook.Middleware { return nil },
+			expCalls:    []string{},
+		},
+
+		"The middlewares should be called in order, the first one being the outermost one.": {
+			middlewares: func(calls *[]string) []webhook.Middleware {
+				return []webhook.Middleware{
+					orderMiddleware("mw1", calls),
+					orderMiddleware("mw2", calls),
+					orderMiddleware("mw3", calls),
+				}
+			},
+			expCalls: []string{"mw1", "mw2", "mw3"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mocks.
+			expResp := &model.ValidatingAdmissionResponse{ID: "test", Allowed: true}
+			mwh := &webhookmock.Webhook{}
+			mwh.On("ID").Return("test-wh")
+			mwh.On("Kind").Return(model.WebhookKind(model.WebhookKindValidating))
+			mwh.On("Review", mock.Anything, mock.Anything).Once().Return(expResp, nil)
+
+			// Execute.
+			calls := []string{}
+			wh := webhook.Chain(mwh, test.middlewares(&calls)...)
+			gotResp, err := wh.Review(context.TODO(), model.AdmissionReview{ID: "test"})
+
+			// Check.
+			if assert.NoError(err) {
+				assert.Equal(expResp, gotResp)
+				assert.Equal("test-wh", wh.ID())
+				assert.Equal(model.WebhookKind(model.WebhookKindValidating), wh.Kind())
+				assert.Equal(test.expCalls, calls)
+			}
+			mwh.AssertExpectations(t)
+		})
+	}
+}
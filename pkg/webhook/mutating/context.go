@@ -0,0 +1,93 @@
+package mutating
+
+import (
+	"context"
+	"sync"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/slok/kubewebhook/v2/pkg/webhook"
+)
+
+type contextKey string
+
+const (
+	// contextOldObjectKey used as unique key to store the old object in the context.
+	contextOldObjectKey = contextKey("kubewebhook-mutating-old-object")
+	// contextAuditAnnotationsKey used as unique key to store the audit annotations in the context.
+	contextAuditAnnotationsKey = contextKey("kubewebhook-mutating-audit-annotations")
+)
+
+// OldObjectFromContext returns the old object of the admission review being mutated
+// (e.g on `update` operations). The returned object is a decoded copy of the old object
+// from the review and has the same type as the object received by the mutator.
+//
+// On operations that don't have an old object (e.g `create`) it will return `nil`.
+func OldObjectFromContext(ctx context.Context) metav1.Object {
+	obj, ok := ctx.Value(contextOldObjectKey).(metav1.Object)
+	if !ok {
+		return nil
+	}
+
+	return obj
+}
+
+// UserInfoFromContext returns the user info (username, UID, groups and extra) of the user that
+// made the request of the admission review being reviewed, this can be used by the code that
+// only receives the context (e.g authorization aware policies). Validating webhooks set the review
+// on the context too, so it can be used by the validators.
+//
+// If the context is not from a webhook review it will return an empty user info.
+func UserInfoFromContext(ctx context.Context) authenticationv1.UserInfo {
+	ar := webhook.ReviewFromContext(ctx)
+	if ar == nil {
+		return authenticationv1.UserInfo{}
+	}
+
+	return ar.UserInfo
+}
+
+func contextWithOldObject(parent context.Context, obj metav1.Object) context.Context {
+	return context.WithValue(parent, contextOldObjectKey, obj)
+}
+
+// auditAnnotations are the audit annotations set by the mutators using the context.
+type auditAnnotations struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (a *auditAnnotations) set(key, value string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.values == nil {
+		a.values = map[string]string{}
+	}
+	a.values[key] = value
+}
+
+func (a *auditAnnotations) get() map[string]string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.values
+}
+
+// SetAuditAnnotation sets an audit annotation on the admission response of the admission review
+// being mutated, these will be recorded on the apiserver audit logs (e.g `sidecar-injected: "true"`).
+// The audit annotations returned on the mutator result have priority over these.
+//
+// If the context is not from a mutating webhook review, it will be ignored.
+func SetAuditAnnotation(ctx context.Context, key, value string) {
+	a, ok := ctx.Value(contextAuditAnnotationsKey).(*auditAnnotations)
+	if !ok {
+		return
+	}
+
+	a.set(key, value)
+}
+
+func contextWithAuditAnnotations(parent context.Context) (context.Context, *auditAnnotations) {
+	a := &auditAnnotations{}
+	return context.WithValue(parent, contextAuditAnnotationsKey, a), a
+}
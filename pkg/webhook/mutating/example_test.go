@@ -2,12 +2,17 @@ package mutating_test
 
 import (
 	"context"
+	"fmt"
+	"net/http"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 
 	"github.com/slok/kubewebhook/v2/pkg/log"
 	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/webhook"
 	"github.com/slok/kubewebhook/v2/pkg/webhook/mutating"
 )
 
@@ -68,3 +73,158 @@ func ExampleMutator_chainMutatingWebhook() {
 		Mutator: mutating.NewChain(log.Noop, fakeMut, fakeMut2, fakeMut3),
 	})
 }
+
+// createOnlyMutatingWebhook shows how you would create a mutator that only mutates
+// on specific operations, using the received admission review.
+func ExampleMutator_createOnlyMutatingWebhook() {
+	sidecarMut := mutating.MutatorFunc(func(_ context.Context, ar *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+		// Only inject on creation, we don't want to churn running pods.
+		if ar.Operation != model.OperationCreate {
+			return &mutating.MutatorResult{}, nil
+		}
+
+		pod, ok := obj.(*corev1.Pod)
+		if !ok {
+			return &mutating.MutatorResult{}, nil
+		}
+
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{
+			Name:  "sidecar",
+			Image: "sidecar:latest",
+		})
+
+		return &mutating.MutatorResult{MutatedObject: pod}, nil
+	})
+
+	_, _ = mutating.NewWebhook(mutating.WebhookConfig{
+		ID:      "podSidecarWebhook",
+		Obj:     &corev1.Pod{},
+		Mutator: sidecarMut,
+	})
+}
+
+// warningsMutatingWebhook shows how you would create a mutator that warns the user about
+// deprecated usages while defaulting them, without rejecting the resource.
+// Warnings are only returned on `v1` admission reviews.
+func ExampleMutator_warningsMutatingWebhook() {
+	deprecatedAnnotation := "example.io/old-annotation"
+	newAnnotation := "example.io/new-annotation"
+
+	defaultMut := mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+		annotations := obj.GetAnnotations()
+		v, ok := annotations[deprecatedAnnotation]
+		if !ok {
+			return &mutating.MutatorResult{NoMutation: true}, nil
+		}
+
+		// Default the new annotation with the deprecated one.
+		delete(annotations, deprecatedAnnotation)
+		annotations[newAnnotation] = v
+		obj.SetAnnotations(annotations)
+
+		return &mutating.MutatorResult{
+			MutatedObject: obj,
+			Warnings:      []string{fmt.Sprintf("%q annotation is deprecated, defaulting to %q", deprecatedAnnotation, newAnnotation)},
+		}, nil
+	})
+
+	_, _ = mutating.NewWebhook(mutating.WebhookConfig{
+		ID:      "annotationDefaulterWebhook",
+		Mutator: defaultMut,
+	})
+}
+
+// denyMutatingWebhook shows how you would create a mutator that denies the admission of the
+// resources that can't be mutated safely, instead of failing with an internal error.
+func ExampleMutator_denyMutatingWebhook() {
+	denyMut := mutating.MutatorFunc(func(_ context.Context, ar *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok {
+			return &mutating.MutatorResult{NoMutation: true}, nil
+		}
+
+		// We can't inject the sidecar on pods that use the host network.
+		if pod.Spec.HostNetwork {
+			msg := fmt.Sprintf("%s/%s pod can't use host network with the sidecar", pod.Namespace, pod.Name)
+			return nil, webhook.NewStatusError(http.StatusForbidden, metav1.StatusReasonForbidden, msg)
+		}
+
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: "sidecar", Image: "sidecar:latest"})
+
+		return &mutating.MutatorResult{MutatedObject: pod}, nil
+	})
+
+	_, _ = mutating.NewWebhook(mutating.WebhookConfig{
+		ID:      "sidecarInjectorWebhook",
+		Obj:     &corev1.Pod{},
+		Mutator: denyMut,
+	})
+}
+
+// customSchemeMutatingWebhook shows how you would create a dynamic webhook that receives
+// custom resources (e.g CRDs) as their typed Go objects, registering them on a custom scheme.
+func ExampleMutator_customSchemeMutatingWebhook() {
+	// Register the Kubernetes core types and our custom types (normally generated `AddToScheme`).
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	// _ = mycrdv1.AddToScheme(scheme)
+
+	mut := mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+		switch o := obj.(type) {
+		case *corev1.Pod:
+			o.Labels = map[string]string{"kind": "pod"}
+		// case *mycrdv1.Foo:
+		default:
+			// Not registered types will be received as unstructured.
+			return &mutating.MutatorResult{NoMutation: true}, nil
+		}
+
+		return &mutating.MutatorResult{MutatedObject: obj}, nil
+	})
+
+	_, _ = mutating.NewWebhook(mutating.WebhookConfig{
+		ID:      "customSchemeWebhook",
+		Mutator: mut,
+		Scheme:  scheme,
+	})
+}
+
+// gvkRouterMutatingWebhook shows how you would create a dynamic webhook that mutates
+// different types with a different mutator for each type.
+func ExampleMutator_gvkRouterMutatingWebhook() {
+	// Add a sidecar container to the pods.
+	sidecarMut := mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok {
+			return &mutating.MutatorResult{}, nil
+		}
+
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: "sidecar", Image: "sidecar:latest"})
+
+		return &mutating.MutatorResult{MutatedObject: pod}, nil
+	})
+
+	// Add an annotation to the services.
+	svcAnnotateMut := mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+		annotations := obj.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations["mutated"] = "true"
+		obj.SetAnnotations(annotations)
+
+		return &mutating.MutatorResult{MutatedObject: obj}, nil
+	})
+
+	// Route each kind to its mutator, the rest of the kinds will not be mutated.
+	router := mutating.NewGVKRouter(log.Noop, map[metav1.GroupVersionKind]mutating.Mutator{
+		{Version: "v1", Kind: "Pod"}:     sidecarMut,
+		{Version: "v1", Kind: "Service"}: svcAnnotateMut,
+	}, nil)
+
+	// Create a dynamic webhook (no object type).
+	_, _ = mutating.NewWebhook(mutating.WebhookConfig{
+		ID:      "multiKindMutatingWebhook",
+		Mutator: router,
+	})
+}
@@ -2,32 +2,58 @@ package mutating
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/slok/kubewebhook/v2/pkg/log"
 	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/webhook/internal/helpers"
 )
 
-// todo
+// JsonPatchOperation is a JSON patch (RFC 6902) operation.
 type JsonPatchOperation struct {
 	Operation string      `json:"op"`
 	Path      string      `json:"path"`
 	Value     interface{} `json:"value,omitempty"`
 }
 
+// ErrAllowOnError can be returned (or wrapped) by the mutators in case of a non fatal error (e.g an
+// optional mutation failed), the error will be logged and the resource will be allowed without mutation,
+// instead of failing the admission review.
+var ErrAllowOnError = errors.New("allow on error")
+
 // MutatorResult is the result of a mutator.
 type MutatorResult struct {
-	// StopChain will stop the chain of validators in case there is a chain set.
+	// StopChain will stop the chain of mutators in case there is a chain set, the next mutators
+	// of the chain will not be called. The mutations of the previous mutators (including the
+	// one that stops the chain) will not be lost.
 	StopChain bool
-	// todo
+	// NoMutation tells the webhook that the mutator didn't mutate the object, so the webhook
+	// can skip the object patch computation and respond without any patch (except the `JsonPatch`
+	// operations). On chains, the object patch computation will be skipped only if none of the
+	// called mutators mutated the object.
+	NoMutation bool
+	// JsonPatch are JSON patch operations that will be added to the mutation patch after the
+	// object mutation operations. This can be used by mutators that already have the patch (e.g
+	// computed out of band), the operations must be valid for the object being mutated. On chains,
+	// the operations of all the mutators will be accumulated.
+	//
+	// To use only these operations as the patch, skipping the object patch computation, set `NoMutation`.
+	// Kubernetes only supports JSON patches on mutations, other patch types (e.g merge patch) are
+	// not supported.
 	JsonPatch []JsonPatchOperation
 	// MutatedObject is the object that has been mutated. If is nil, it will be used the one
-	// received by the Mutator.
+	// received by the Mutator. It can be the received object mutated in place or a brand new
+	// object of the same type, the patch will be computed from the original object to this one.
 	MutatedObject metav1.Object
 	// Warnings are special messages that can be set to warn the user (e.g deprecation messages, almost invalid resources...).
+	// Warnings are only supported by `v1` admission reviews, on `v1beta1` they will be ignored.
 	Warnings []string
+	// AuditAnnotations are key values that will be added to the audit event of the request on
+	// the apiserver audit logs, the keys will be prefixed by the apiserver with the webhook name.
+	AuditAnnotations map[string]string
 }
 
 // Mutator knows how to mutate the received kubernetes object.
@@ -40,7 +66,14 @@ type Mutator interface {
 	// Also recieves the webhook admission review in case it wants more context and
 	// information of the review.
 	// Mutators can be grouped in chains, that's why we have a `StopChain` boolean
-	// in the result, to stop executing the validators chain.
+	// in the result, to stop executing the mutators chain.
+	// Mutators with side effects (e.g calling external APIs) should check the review
+	// `DryRun` flag, dry-run requests will not be persisted by the apiserver.
+	// Mutators that need to deny the admission of the resource (e.g it can't be mutated
+	// safely) can return a `webhook.StatusError` with the denial status (e.g 403 Forbidden),
+	// any other error will deny it as an internal error.
+	// Mutators must honor the context cancellation (e.g review timeouts), a mutator that
+	// ignores it can't be stopped and will keep running after the review has ended.
 	Mutate(ctx context.Context, ar *model.AdmissionReview, obj metav1.Object) (result *MutatorResult, err error)
 }
 
@@ -54,6 +87,16 @@ func (f MutatorFunc) Mutate(ctx context.Context, ar *model.AdmissionReview, obj
 	return f(ctx, ar, obj)
 }
 
+// NoopMutator is a mutator that doesn't mutate, it can be used as a placeholder (e.g
+// on conditional chains) or for testing.
+const NoopMutator = noopMutator(0)
+
+type noopMutator int
+
+func (noopMutator) Mutate(_ context.Context, _ *model.AdmissionReview, _ metav1.Object) (*MutatorResult, error) {
+	return &MutatorResult{NoMutation: true}, nil
+}
+
 // Chain is a chain of mutators that will execute secuentially all the
 // mutators that have been added to it. It satisfies Mutator interface.
 type Chain struct {
@@ -72,7 +115,9 @@ func NewChain(logger log.Logger, mutators ...Mutator) *Chain {
 // Mutate will execute all the mutation chain.
 func (c *Chain) Mutate(ctx context.Context, ar *model.AdmissionReview, obj metav1.Object) (*MutatorResult, error) {
 	var warnings []string
+	var auditAnnotations map[string]string
 	var jsonPatchOps []JsonPatchOperation
+	mutated := false
 	for _, mt := range c.mutators {
 		select {
 		case <-ctx.Done():
@@ -84,7 +129,7 @@ func (c *Chain) Mutate(ctx context.Context, ar *model.AdmissionReview, obj metav
 			}
 
 			if res == nil {
-				return nil, fmt.Errorf("validator result can't be `nil`")
+				return nil, fmt.Errorf("mutator result can't be `nil`")
 			}
 
 			if res.JsonPatch != nil && jsonPatchOps == nil {
@@ -92,7 +137,9 @@ func (c *Chain) Mutate(ctx context.Context, ar *model.AdmissionReview, obj metav
 			}
 
 			// Don't lose the data through the chain, set warnings and pass around the mutated object.
+			mutated = mutated || !res.NoMutation
 			warnings = append(warnings, res.Warnings...)
+			auditAnnotations = helpers.MergeAuditAnnotations(auditAnnotations, res.AuditAnnotations)
 			if res.JsonPatch != nil {
 				jsonPatchOps = append(jsonPatchOps, res.JsonPatch...)
 			}
@@ -100,9 +147,12 @@ func (c *Chain) Mutate(ctx context.Context, ar *model.AdmissionReview, obj metav
 				obj = res.MutatedObject
 			}
 
-
 			if res.StopChain {
+				// Don't lose the previous mutators mutated object.
+				res.MutatedObject = obj
+				res.NoMutation = !mutated
 				res.Warnings = warnings
+				res.AuditAnnotations = auditAnnotations
 				res.JsonPatch = jsonPatchOps
 				return res, nil
 			}
@@ -110,8 +160,10 @@ func (c *Chain) Mutate(ctx context.Context, ar *model.AdmissionReview, obj metav
 	}
 
 	return &MutatorResult{
-		MutatedObject: obj,
-		Warnings:      warnings,
-		JsonPatch:     jsonPatchOps,
+		MutatedObject:    obj,
+		NoMutation:       !mutated,
+		Warnings:         warnings,
+		AuditAnnotations: auditAnnotations,
+		JsonPatch:        jsonPatchOps,
 	}, nil
 }
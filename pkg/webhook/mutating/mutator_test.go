@@ -20,6 +20,7 @@ func TestMutatorChain(t *testing.T) {
 		name         string
 		initalObj    metav1.Object
 		mutatorMocks func() []mutating.Mutator
+		cancelCtx    bool
 		expResult    *mutating.MutatorResult
 		expErr       bool
 	}{
@@ -58,6 +59,55 @@ func TestMutatorChain(t *testing.T) {
 			},
 		},
 
+		"If none of the mutators mutated, the result should not have mutations.": {
+			mutatorMocks: func() []mutating.Mutator {
+				m1, m2 := &mutatingmock.Mutator{}, &mutatingmock.Mutator{}
+				m1.On("Mutate", mock.Anything, mock.Anything, mock.Anything).Return(&mutating.MutatorResult{NoMutation: true}, nil)
+				m2.On("Mutate", mock.Anything, mock.Anything, mock.Anything).Return(&mutating.MutatorResult{NoMutation: true}, nil)
+				return []mutating.Mutator{m1, m2}
+			},
+			expResult: &mutating.MutatorResult{NoMutation: true},
+		},
+
+		"If any of the mutators mutated, the result should have mutations.": {
+			mutatorMocks: func() []mutating.Mutator {
+				m1, m2 := &mutatingmock.Mutator{}, &mutatingmock.Mutator{}
+				m1.On("Mutate", mock.Anything, mock.Anything, mock.Anything).Return(&mutating.MutatorResult{NoMutation: true}, nil)
+				m2.On("Mutate", mock.Anything, mock.Anything, mock.Anything).Return(&mutating.MutatorResult{}, nil)
+				return []mutating.Mutator{m1, m2}
+			},
+			expResult: &mutating.MutatorResult{},
+		},
+
+		"Audit annotations shouldn't be lost in the chain.": {
+			mutatorMocks: func() []mutating.Mutator {
+				m1, m2 := &mutatingmock.Mutator{}, &mutatingmock.Mutator{}
+				m1.On("Mutate", mock.Anything, mock.Anything, mock.Anything).Return(&mutating.MutatorResult{NoMutation: true, AuditAnnotations: map[string]string{"k1": "v1", "k2": "v2"}}, nil)
+				m2.On("Mutate", mock.Anything, mock.Anything, mock.Anything).Return(&mutating.MutatorResult{NoMutation: true, AuditAnnotations: map[string]string{"k2": "v2b"}}, nil)
+				return []mutating.Mutator{m1, m2}
+			},
+			expResult: &mutating.MutatorResult{
+				NoMutation:       true,
+				AuditAnnotations: map[string]string{"k1": "v1", "k2": "v2b"},
+			},
+		},
+
+		"JSON patch operations should be accumulated in the chain.": {
+			mutatorMocks: func() []mutating.Mutator {
+				m1, m2, m3 := &mutatingmock.Mutator{}, &mutatingmock.Mutator{}, &mutatingmock.Mutator{}
+				m1.On("Mutate", mock.Anything, mock.Anything, mock.Anything).Return(&mutating.MutatorResult{JsonPatch: []mutating.JsonPatchOperation{{Operation: "add", Path: "/a", Value: "1"}}}, nil)
+				m2.On("Mutate", mock.Anything, mock.Anything, mock.Anything).Return(&mutating.MutatorResult{}, nil)
+				m3.On("Mutate", mock.Anything, mock.Anything, mock.Anything).Return(&mutating.MutatorResult{JsonPatch: []mutating.JsonPatchOperation{{Operation: "remove", Path: "/b"}}}, nil)
+				return []mutating.Mutator{m1, m2, m3}
+			},
+			expResult: &mutating.MutatorResult{
+				JsonPatch: []mutating.JsonPatchOperation{
+					{Operation: "add", Path: "/a", Value: "1"},
+					{Operation: "remove", Path: "/b"},
+				},
+			},
+		},
+
 		"In case the last mutator doesn't return any object, the original one should be returned.": {
 			initalObj: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p0"}},
 			mutatorMocks: func() []mutating.Mutator {
@@ -82,6 +132,33 @@ func TestMutatorChain(t *testing.T) {
 			expResult: &mutating.MutatorResult{StopChain: true},
 		},
 
+		"Should stop in the middle of the chain and return the latest mutated object if the stopping mutator doesn't return one.": {
+			initalObj: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p0"}},
+			mutatorMocks: func() []mutating.Mutator {
+				obj0 := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p0"}}
+				obj1 := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p1"}}
+
+				m1, m2, m3 := &mutatingmock.Mutator{}, &mutatingmock.Mutator{}, &mutatingmock.Mutator{}
+				m1.On("Mutate", mock.Anything, mock.Anything, obj0).Return(&mutating.MutatorResult{MutatedObject: obj1}, nil)
+				m2.On("Mutate", mock.Anything, mock.Anything, obj1).Return(&mutating.MutatorResult{StopChain: true}, nil)
+				return []mutating.Mutator{m1, m2, m3}
+			},
+			expResult: &mutating.MutatorResult{
+				StopChain:     true,
+				MutatedObject: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p1"}},
+			},
+		},
+
+		"Should stop in the middle of the chain and have mutations if any of the previous mutators mutated.": {
+			mutatorMocks: func() []mutating.Mutator {
+				m1, m2, m3 := &mutatingmock.Mutator{}, &mutatingmock.Mutator{}, &mutatingmock.Mutator{}
+				m1.On("Mutate", mock.Anything, mock.Anything, mock.Anything).Return(&mutating.MutatorResult{}, nil)
+				m2.On("Mutate", mock.Anything, mock.Anything, mock.Anything).Return(&mutating.MutatorResult{StopChain: true, NoMutation: true}, nil)
+				return []mutating.Mutator{m1, m2, m3}
+			},
+			expResult: &mutating.MutatorResult{StopChain: true},
+		},
+
 		"In case of error the chain should be stopped.": {
 			mutatorMocks: func() []mutating.Mutator {
 				m1, m2, m3, m4, m5 := &mutatingmock.Mutator{}, &mutatingmock.Mutator{}, &mutatingmock.Mutator{}, &mutatingmock.Mutator{}, &mutatingmock.Mutator{}
@@ -92,6 +169,15 @@ func TestMutatorChain(t *testing.T) {
 			},
 			expErr: true,
 		},
+
+		"In case of the context being done, the chain should not execute the mutators.": {
+			cancelCtx: true,
+			mutatorMocks: func() []mutating.Mutator {
+				m1, m2 := &mutatingmock.Mutator{}, &mutatingmock.Mutator{}
+				return []mutating.Mutator{m1, m2}
+			},
+			expErr: true,
+		},
 	}
 
 	for _, test := range tests {
@@ -102,8 +188,14 @@ func TestMutatorChain(t *testing.T) {
 			mutators := test.mutatorMocks()
 
 			// Execute.
+			ctx := context.TODO()
+			if test.cancelCtx {
+				c, cancel := context.WithCancel(ctx)
+				cancel()
+				ctx = c
+			}
 			chain := mutating.NewChain(log.Noop, mutators...)
-			res, err := chain.Mutate(context.TODO(), nil, test.initalObj)
+			res, err := chain.Mutate(ctx, nil, test.initalObj)
 
 			// Check result.
 			if test.expErr {
@@ -120,3 +212,18 @@ func TestMutatorChain(t *testing.T) {
 		})
 	}
 }
+
+func TestNoopMutator(t *testing.T) {
+	assert := assert.New(t)
+
+	obj := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p0"}}
+	chain := mutating.NewChain(log.Noop, mutating.NoopMutator, mutating.NoopMutator)
+	res, err := chain.Mutate(context.TODO(), nil, obj)
+	if assert.NoError(err) {
+		exp := &mutating.MutatorResult{
+			NoMutation:    true,
+			MutatedObject: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p0"}},
+		}
+		assert.Equal(exp, res)
+	}
+}
@@ -0,0 +1,86 @@
+package mutating
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gomodules.xyz/jsonpatch/v3"
+
+	"github.com/slok/kubewebhook/v2/pkg/model"
+)
+
+// PatchComputer knows how to compute the JSON patch operations that transform the original
+// JSON object into the mutated JSON object.
+type PatchComputer interface {
+	ComputePatch(original, mutated []byte) ([]JsonPatchOperation, error)
+}
+
+// PatchComputerFunc is a helper type to create patch computers from functions.
+type PatchComputerFunc func(original, mutated []byte) ([]JsonPatchOperation, error)
+
+// ComputePatch satisfies PatchComputer interface.
+func (f PatchComputerFunc) ComputePatch(original, mutated []byte) ([]JsonPatchOperation, error) {
+	return f(original, mutated)
+}
+
+// defPatchComputer is the default patch computer, uses `gomodules.xyz/jsonpatch`.
+var defPatchComputer = PatchComputerFunc(func(original, mutated []byte) ([]JsonPatchOperation, error) {
+	patch, err := jsonpatch.CreatePatch(original, mutated)
+	if err != nil {
+		return nil, err
+	}
+
+	ops := make([]JsonPatchOperation, 0, len(patch))
+	for _, op := range patch {
+		ops = append(ops, JsonPatchOperation{Operation: op.Operation, Path: op.Path, Value: op.Value})
+	}
+
+	return ops, nil
+})
+
+// NewPatchResponse returns a mutating admission response with the JSON patch operations, this
+// can be used to create the responses of custom webhooks that already have the patch.
+func NewPatchResponse(id string, ops []JsonPatchOperation) (*model.MutatingAdmissionResponse, error) {
+	patch, err := marshalJSONPatch(toJSONPatch(ops))
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.MutatingAdmissionResponse{
+		ID:             id,
+		JSONPatchPatch: patch,
+	}, nil
+}
+
+// NewAllowResponse returns a mutating admission response that allows the resource without
+// mutation, this can be used to create the responses of custom webhooks.
+//
+// Mutating responses can't deny the resource, to deny it return a `webhook.StatusError`
+// (e.g 403 Forbidden) as the review error.
+func NewAllowResponse(id string) *model.MutatingAdmissionResponse {
+	return &model.MutatingAdmissionResponse{ID: id}
+}
+
+// PatchFromResponse returns the JSON patch operations of a mutating admission response,
+// this can be useful to inspect the operations a mutating webhook has produced (e.g tests,
+// debugging...).
+//
+// If the response doesn't have a patch it will return an empty list of operations.
+func PatchFromResponse(resp model.AdmissionResponse) ([]JsonPatchOperation, error) {
+	mresp, ok := resp.(*model.MutatingAdmissionResponse)
+	if !ok {
+		return nil, fmt.Errorf("response is not a mutating admission response")
+	}
+
+	ops := []JsonPatchOperation{}
+	if len(mresp.JSONPatchPatch) == 0 {
+		return ops, nil
+	}
+
+	err := json.Unmarshal(mresp.JSONPatchPatch, &ops)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal JSON patch: %w", err)
+	}
+
+	return ops, nil
+}
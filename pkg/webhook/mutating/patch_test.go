@@ -0,0 +1,100 @@
+package mutating_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/webhook/mutating"
+)
+
+func TestNewPatchResponse(t *testing.T) {
+	tests := map[string]struct {
+		ops     []mutating.JsonPatchOperation
+		expResp *model.MutatingAdmissionResponse
+	}{
+		"Without operations it should return a response without patch.": {
+			ops:     []mutating.JsonPatchOperation{},
+			expResp: &model.MutatingAdmissionResponse{ID: "test"},
+		},
+
+		"With operations it should return a response with the JSON patch.": {
+			ops: []mutating.JsonPatchOperation{
+				{Operation: "add", Path: "/metadata/labels/team", Value: "team1"},
+				{Operation: "replace", Path: "/spec/test", Value: nil},
+				{Operation: "remove", Path: "/metadata/annotations/test"},
+			},
+			expResp: &model.MutatingAdmissionResponse{
+				ID:             "test",
+				JSONPatchPatch: []byte(`[{"op":"add","path":"/metadata/labels/team","value":"team1"},{"op":"replace","path":"/spec/test","value":null},{"op":"remove","path":"/metadata/annotations/test"}]`),
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			gotResp, err := mutating.NewPatchResponse("test", test.ops)
+			if assert.NoError(err) {
+				assert.Equal(test.expResp, gotResp)
+			}
+		})
+	}
+}
+
+func TestNewAllowResponse(t *testing.T) {
+	assert := assert.New(t)
+
+	gotResp := mutating.NewAllowResponse("test")
+	assert.Equal(&model.MutatingAdmissionResponse{ID: "test"}, gotResp)
+}
+
+func TestPatchFromResponse(t *testing.T) {
+	tests := map[string]struct {
+		resp   model.AdmissionResponse
+		expOps []mutating.JsonPatchOperation
+		expErr bool
+	}{
+		"A non mutating response should fail.": {
+			resp:   &model.ValidatingAdmissionResponse{ID: "test", Allowed: true},
+			expErr: true,
+		},
+
+		"A mutating response without patch should return an empty patch.": {
+			resp:   &model.MutatingAdmissionResponse{ID: "test"},
+			expOps: []mutating.JsonPatchOperation{},
+		},
+
+		"A mutating response with an invalid patch should fail.": {
+			resp:   &model.MutatingAdmissionResponse{ID: "test", JSONPatchPatch: []byte(`{"op":"add"}`)},
+			expErr: true,
+		},
+
+		"A mutating response with a patch should return the patch operations.": {
+			resp: &model.MutatingAdmissionResponse{
+				ID:             "test",
+				JSONPatchPatch: []byte(`[{"op":"add","path":"/metadata/labels/team","value":"team1"},{"op":"remove","path":"/metadata/annotations/test"}]`),
+			},
+			expOps: []mutating.JsonPatchOperation{
+				{Operation: "add", Path: "/metadata/labels/team", Value: "team1"},
+				{Operation: "remove", Path: "/metadata/annotations/test"},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			gotOps, err := mutating.PatchFromResponse(test.resp)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expOps, gotOps)
+			}
+		})
+	}
+}
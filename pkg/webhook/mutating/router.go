@@ -0,0 +1,73 @@
+package mutating
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/slok/kubewebhook/v2/pkg/log"
+	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/webhook/internal/helpers"
+)
+
+// GVKRouter is a mutator that routes the mutation to a different mutator based on the
+// kind (GVK) of the reviewed object, this is useful on dynamic webhooks that
+// mutate multiple types. It satisfies Mutator interface.
+type GVKRouter struct {
+	routes   map[metav1.GroupVersionKind]Mutator
+	fallback Mutator
+	logger   log.Logger
+}
+
+// NewGVKRouter returns a new GVKRouter. The reviews whose kind doesn't match any of the routes
+// will be mutated by the fallback mutator, if the fallback is `nil` they will not be mutated.
+//
+// Empty group and version on the routes will match any group and version (e.g `{Kind: "Pod"}`
+// will match all the pods).
+func NewGVKRouter(logger log.Logger, routes map[metav1.GroupVersionKind]Mutator, fallback Mutator) *GVKRouter {
+	if logger == nil {
+		logger = log.Noop
+	}
+
+	return &GVKRouter{
+		routes:   routes,
+		fallback: fallback,
+		logger:   logger,
+	}
+}
+
+// Mutate will execute the mutator that matches the admission review kind.
+func (r *GVKRouter) Mutate(ctx context.Context, ar *model.AdmissionReview, obj metav1.Object) (*MutatorResult, error) {
+	if m, ok := r.route(*ar); ok {
+		return m.Mutate(ctx, ar, obj)
+	}
+
+	if r.fallback == nil {
+		r.logger.WithCtxValues(ctx).Debugf("No mutator route for the kind, ignoring mutation")
+		return &MutatorResult{NoMutation: true}, nil
+	}
+
+	return r.fallback.Mutate(ctx, ar, obj)
+}
+
+// route returns the mutator of the admission review kind. The kind of the reviewed object (could be converted
+// from the requested kind, e.g `matchPolicy: Equivalent`) has priority over the requested kind, and the routes
+// with empty group or version will match any group or version.
+func (r *GVKRouter) route(ar model.AdmissionReview) (Mutator, bool) {
+	gvks := helpers.ReviewGVKs(ar)
+	for _, gvk := range gvks {
+		if m, ok := r.routes[gvk]; ok {
+			return m, true
+		}
+	}
+
+	for _, gvk := range gvks {
+		for rgvk, m := range r.routes {
+			if helpers.GVKMatches(rgvk, gvk) {
+				return m, true
+			}
+		}
+	}
+
+	return nil, false
+}
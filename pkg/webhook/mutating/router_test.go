@@ -0,0 +1,135 @@
+package mutating_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/slok/kubewebhook/v2/pkg/log"
+	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/webhook/mutating"
+	"github.com/slok/kubewebhook/v2/pkg/webhook/mutating/mutatingmock"
+)
+
+func TestGVKRouter(t *testing.T) {
+	podGVK := metav1.GroupVersionKind{Kind: "Pod"}
+	svcGVK := metav1.GroupVersionKind{Version: "v1", Kind: "Service"}
+	deployGVK := metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+
+	tests := map[string]struct {
+		review    model.AdmissionReview
+		nilLogger bool
+		fallback  bool
+		mock      func(mpod, msvc, mfallback *mutatingmock.Mutator)
+		expResult *mutating.MutatorResult
+	}{
+		"A review of a routed kind should be mutated by the kind mutator.": {
+			review: model.AdmissionReview{RequestGVK: &svcGVK},
+			mock: func(mpod, msvc, mfallback *mutatingmock.Mutator) {
+				msvc.On("Mutate", mock.Anything, mock.Anything, mock.Anything).Once().Return(&mutating.MutatorResult{Warnings: []string{"svc"}}, nil)
+			},
+			expResult: &mutating.MutatorResult{Warnings: []string{"svc"}},
+		},
+
+		"A review of a kind that matches a route without group and version should be mutated by the route mutator.": {
+			review: model.AdmissionReview{RequestGVK: &metav1.GroupVersionKind{Version: "v1", Kind: "Pod"}},
+			mock: func(mpod, msvc, mfallback *mutatingmock.Mutator) {
+				mpod.On("Mutate", mock.Anything, mock.Anything, mock.Anything).Once().Return(&mutating.MutatorResult{Warnings: []string{"pod"}}, nil)
+			},
+			expResult: &mutating.MutatorResult{Warnings: []string{"pod"}},
+		},
+
+		"A review of an object converted from the requested kind should be mutated by the object kind mutator.": {
+			review: model.AdmissionReview{
+				RequestGVK:   &metav1.GroupVersionKind{Version: "v1beta1", Kind: "Service"},
+				NewObjectRaw: []byte(`{"kind":"Service","apiVersion":"v1"}`),
+			},
+			mock: func(mpod, msvc, mfallback *mutatingmock.Mutator) {
+				msvc.On("Mutate", mock.Anything, mock.Anything, mock.Anything).Once().Return(&mutating.MutatorResult{Warnings: []string{"svc"}}, nil)
+			},
+			expResult: &mutating.MutatorResult{Warnings: []string{"svc"}},
+		},
+
+		"A review of a deleted object converted from the requested kind should be mutated by the object kind mutator.": {
+			review: model.AdmissionReview{
+				Operation:    model.OperationDelete,
+				RequestGVK:   &metav1.GroupVersionKind{Version: "v1beta1", Kind: "Service"},
+				OldObjectRaw: []byte(`{"kind":"Service","apiVersion":"v1"}`),
+			},
+			mock: func(mpod, msvc, mfallback *mutatingmock.Mutator) {
+				msvc.On("Mutate", mock.Anything, mock.Anything, mock.Anything).Once().Return(&mutating.MutatorResult{Warnings: []string{"svc"}}, nil)
+			},
+			expResult: &mutating.MutatorResult{Warnings: []string{"svc"}},
+		},
+
+		"A review of a not routed kind without fallback should not be mutated.": {
+			review:    model.AdmissionReview{RequestGVK: &deployGVK},
+			mock:      func(mpod, msvc, mfallback *mutatingmock.Mutator) {},
+			expResult: &mutating.MutatorResult{NoMutation: true},
+		},
+
+		"A review of a not routed kind without fallback and without logger should not be mutated.": {
+			review:    model.AdmissionReview{RequestGVK: &deployGVK},
+			nilLogger: true,
+			mock:      func(mpod, msvc, mfallback *mutatingmock.Mutator) {},
+			expResult: &mutating.MutatorResult{NoMutation: true},
+		},
+
+		"A review of a not routed kind with fallback should be mutated by the fallback mutator.": {
+			review:   model.AdmissionReview{RequestGVK: &deployGVK},
+			fallback: true,
+			mock: func(mpod, msvc, mfallback *mutatingmock.Mutator) {
+				mfallback.On("Mutate", mock.Anything, mock.Anything, mock.Anything).Once().Return(&mutating.MutatorResult{Warnings: []string{"fallback"}}, nil)
+			},
+			expResult: &mutating.MutatorResult{Warnings: []string{"fallback"}},
+		},
+
+		"A review without kind with fallback should be mutated by the fallback mutator.": {
+			review:   model.AdmissionReview{},
+			fallback: true,
+			mock: func(mpod, msvc, mfallback *mutatingmock.Mutator) {
+				mfallback.On("Mutate", mock.Anything, mock.Anything, mock.Anything).Once().Return(&mutating.MutatorResult{Warnings: []string{"fallback"}}, nil)
+			},
+			expResult: &mutating.MutatorResult{Warnings: []string{"fallback"}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mocks.
+			mpod, msvc, mfallback := &mutatingmock.Mutator{}, &mutatingmock.Mutator{}, &mutatingmock.Mutator{}
+			test.mock(mpod, msvc, mfallback)
+
+			// Prepare.
+			var fallback mutating.Mutator
+			if test.fallback {
+				fallback = mfallback
+			}
+			routes := map[metav1.GroupVersionKind]mutating.Mutator{
+				podGVK: mpod,
+				svcGVK: msvc,
+			}
+			var logger log.Logger = log.Noop
+			if test.nilLogger {
+				logger = nil
+			}
+			router := mutating.NewGVKRouter(logger, routes, fallback)
+
+			// Execute.
+			gotResult, err := router.Mutate(context.TODO(), &test.review, nil)
+
+			// Check.
+			if assert.NoError(err) {
+				assert.Equal(test.expResult, gotResult)
+			}
+			mpod.AssertExpectations(t)
+			msvc.AssertExpectations(t)
+			mfallback.AssertExpectations(t)
+		})
+	}
+}
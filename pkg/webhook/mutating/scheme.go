@@ -0,0 +1,21 @@
+package mutating
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+// DefaultScheme returns a new scheme with all the Kubernetes types registered, including the legacy
+// group versions (e.g `extensions/v1beta1`, `apps/v1beta1`). These are the types inferred by default
+// on the dynamic webhooks, the returned scheme can be extended with custom types (e.g CRDs) and set
+// as the webhook `Scheme`.
+func DefaultScheme() (*runtime.Scheme, error) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return nil, fmt.Errorf("could not register Kubernetes types on the scheme: %w", err)
+	}
+
+	return scheme, nil
+}
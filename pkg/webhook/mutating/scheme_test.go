@@ -0,0 +1,59 @@
+package mutating_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/webhook/mutating"
+)
+
+func TestDefaultScheme(t *testing.T) {
+	tests := map[string]struct {
+		raw     []byte
+		expType interface{}
+	}{
+		"A legacy extensions/v1beta1 ingress should be decoded with its type.": {
+			raw:     []byte(`{"kind":"Ingress","apiVersion":"extensions/v1beta1","metadata":{"name":"test"}}`),
+			expType: &extensionsv1beta1.Ingress{},
+		},
+
+		"A custom type registered on the default scheme should be decoded with its type.": {
+			raw:     []byte(`{"kind":"Foo","apiVersion":"example.io/v1","metadata":{"name":"test"}}`),
+			expType: &corev1.ConfigMap{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			scheme, err := mutating.DefaultScheme()
+			require.NoError(err)
+			scheme.AddKnownTypeWithName(schema.GroupVersionKind{Group: "example.io", Version: "v1", Kind: "Foo"}, &corev1.ConfigMap{})
+
+			var gotObj metav1.Object
+			wh, err := mutating.NewWebhook(mutating.WebhookConfig{
+				ID:     "test",
+				Scheme: scheme,
+				Mutator: mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+					gotObj = obj
+					return &mutating.MutatorResult{NoMutation: true}, nil
+				}),
+			})
+			require.NoError(err)
+
+			_, err = wh.Review(context.TODO(), model.AdmissionReview{ID: "test", NewObjectRaw: test.raw})
+			require.NoError(err)
+			assert.IsType(test.expType, gotObj)
+		})
+	}
+}
@@ -3,12 +3,20 @@ package mutating
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"regexp"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
 	"gomodules.xyz/jsonpatch/v3"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 
 	"github.com/slok/kubewebhook/v2/pkg/log"
 	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/tracing"
 	"github.com/slok/kubewebhook/v2/pkg/webhook"
 	"github.com/slok/kubewebhook/v2/pkg/webhook/internal/helpers"
 )
@@ -18,12 +26,49 @@ type WebhookConfig struct {
 	// ID is the id of the webhook.
 	ID string
 	// Object is the object of the webhook, to use multiple types on the same webhook or
-	// type inference, don't set this field (will be `nil`).
+	// type inference, don't set this field (will be `nil`). The inferred objects of types not
+	// registered on the scheme (e.g CRDs) will be received as `*unstructured.Unstructured`.
 	Obj metav1.Object
 	// Mutator is the webhook mutator.
 	Mutator Mutator
 	// Logger is the app logger.
 	Logger log.Logger
+	// Tracer is the tracer used to trace the webhook reviews, by default it will not trace.
+	Tracer tracing.Tracer
+	// Scheme is the scheme used to infer the types when `Obj` is not set (e.g CRDs). If
+	// not set it will use the Kubernetes client scheme (Kubernetes types, including the legacy
+	// group versions like `extensions/v1beta1`). When set, the Kubernetes types will need to
+	// be registered on it too if required (e.g `clientgoscheme.AddToScheme` or starting from `DefaultScheme`).
+	Scheme *runtime.Scheme
+	// Decoder is the decoder used to decode the raw objects of the admission review (e.g CRDs with
+	// custom codecs). If not set it will use the universal deserializer of the `Scheme` (or the
+	// object scheme when `Obj` is set). On dynamic webhooks (`Obj` not set) the objects that can't
+	// be decoded will fallback to `*unstructured.Unstructured`.
+	Decoder runtime.Decoder
+	// DisablePanicRecovery will disable the recovery of the mutator panics. By default the panics are
+	// recovered and returned as review errors, so they are handled like any other mutator error (e.g fail
+	// open webhooks will allow them). When disabled, the panics will not be converted to errors and will
+	// reach the HTTP handler (check `http.HandlerConfig.DisablePanicRecovery`).
+	DisablePanicRecovery bool
+	// PatchLogging will log every JSON patch operation of the mutations at info level, with the
+	// operation data as structured values, this can be used to audit the webhook mutations.
+	PatchLogging bool
+	// PatchLoggingRedactPath is a regex that matches the JSON patch operation paths that will have
+	// the value redacted when logging the patch operations (e.g `^/data/` for secrets data). The
+	// operations that set a parent of a matching path (e.g `add /data` with `^/data/`) will be redacted too.
+	PatchLoggingRedactPath *regexp.Regexp
+	// PatchConflictLastWins will resolve the conflicts of the mutation patch (multiple `add` operations on
+	// the same path, e.g mutators on a chain returning JSON patch operations) using the last operation.
+	// By default the conflicts will end in an error.
+	PatchConflictLastWins bool
+	// PatchComputer is the one that computes the JSON patch operations from the received object to the
+	// mutated object, this can be used to work around JSON patch library issues (e.g arrays) with a custom
+	// implementation. By default it will use `gomodules.xyz/jsonpatch`.
+	PatchComputer PatchComputer
+	// AllowOnDecodeError will allow the resources that can't be decoded (including the objects that are not
+	// of the webhook `Obj` type) without mutation, logging the error. This is independent of the mutator errors
+	// (check `ErrAllowOnError`). By default the decode errors will fail the review.
+	AllowOnDecodeError bool
 }
 
 func (c *WebhookConfig) defaults() error {
@@ -38,7 +83,15 @@ func (c *WebhookConfig) defaults() error {
 	if c.Logger == nil {
 		c.Logger = log.Noop
 	}
-	c.Logger = c.Logger.WithValues(log.Kv{"webhook-id": c.ID, "webhhok-type": "mutating"})
+	c.Logger = c.Logger.WithValues(log.Kv{"webhook-id": c.ID, "webhook-kind": "mutating"})
+
+	if c.Tracer == nil {
+		c.Tracer = tracing.Noop
+	}
+
+	if c.PatchComputer == nil {
+		c.PatchComputer = defPatchComputer
+	}
 
 	return nil
 }
@@ -49,6 +102,7 @@ type mutatingWebhook struct {
 	mutator       Mutator
 	cfg           WebhookConfig
 	logger        log.Logger
+	tracer        tracing.Tracer
 }
 
 // NewWebhook is a mutating webhook and will return a webhook ready for a type of resource.
@@ -64,9 +118,9 @@ func NewWebhook(cfg WebhookConfig) (webhook.Webhook, error) {
 	// infer the type.
 	var oc helpers.ObjectCreator
 	if cfg.Obj != nil {
-		oc = helpers.NewStaticObjectCreator(cfg.Obj)
+		oc = helpers.NewStaticObjectCreator(cfg.Obj, cfg.Decoder)
 	} else {
-		oc = helpers.NewDynamicObjectCreator()
+		oc = helpers.NewDynamicObjectCreator(cfg.Scheme, cfg.Decoder)
 	}
 
 	return &mutatingWebhook{
@@ -75,6 +129,7 @@ func NewWebhook(cfg WebhookConfig) (webhook.Webhook, error) {
 		mutator:       cfg.Mutator,
 		cfg:           cfg,
 		logger:        cfg.Logger,
+		tracer:        cfg.Tracer,
 	}, nil
 }
 
@@ -82,7 +137,12 @@ func (w mutatingWebhook) ID() string { return w.id }
 
 func (w mutatingWebhook) Kind() model.WebhookKind { return model.WebhookKindMutating }
 
-func (w mutatingWebhook) Review(ctx context.Context, ar model.AdmissionReview) (model.AdmissionResponse, error) {
+func (w mutatingWebhook) Review(ctx context.Context, ar model.AdmissionReview) (_ model.AdmissionResponse, err error) {
+	ctx = w.tracer.NewTrace(ctx, "mutatingWebhook.Review")
+	defer func(ctx context.Context) { w.tracer.EndTrace(ctx, err) }(ctx)
+	w.tracer.SetValuesOnTrace(ctx, helpers.ReviewTraceValues(w.id, ar))
+	ctx = webhook.ContextWithID(ctx, w.id)
+
 	// Delete operations don't have body because should be gone on the deletion, instead they have the body
 	// of the object we want to delete as an old object.
 	raw := ar.NewObjectRaw
@@ -90,15 +150,28 @@ func (w mutatingWebhook) Review(ctx context.Context, ar model.AdmissionReview) (
 		raw = ar.OldObjectRaw
 	}
 
-	// Create a new object from the raw type.
-	runtimeObj, err := w.objectCreator.NewObject(raw)
+	// Subresources can have a different type from the webhook object (e.g `deployments/scale` is a `Scale`),
+	// we can't decode these into the webhook object type, so we don't mutate them. The rest of the objects
+	// with a different type are not expected (e.g webhook misconfiguration).
+	if kind := helpers.ReviewObjectKind(ar, raw); w.cfg.Obj != nil && !helpers.IsKindOfObject(kind, w.cfg.Obj) {
+		if ar.SubResource != "" {
+			w.logger.WithCtxValues(ctx).Debugf("Subresource %q object type is not the webhook object type, ignoring mutation", ar.SubResource)
+			return &model.MutatingAdmissionResponse{ID: ar.ID}, nil
+		}
+
+		return w.decodeError(ctx, ar, helpers.NewUnexpectedKindError(kind, w.cfg.Obj))
+	}
+
+	dctx := w.tracer.NewTrace(ctx, "decode")
+	mutatingObj, oldObj, err := w.decodeObjects(raw, ar.OldObjectRaw)
+	w.tracer.EndTrace(dctx, err)
 	if err != nil {
-		return nil, fmt.Errorf("could not create object from raw: %w", err)
+		return w.decodeError(ctx, ar, err)
 	}
 
-	mutatingObj, ok := runtimeObj.(metav1.Object)
-	if !ok {
-		return nil, fmt.Errorf("impossible to type assert the deep copy to metav1.Object")
+	// If we have an old object (e.g updates), make it available to the mutators.
+	if oldObj != nil {
+		ctx = contextWithOldObject(ctx, oldObj)
 	}
 
 	res, err := w.mutatingAdmissionReview(ctx, ar, raw, mutatingObj)
@@ -107,14 +180,123 @@ func (w mutatingWebhook) Review(ctx context.Context, ar model.AdmissionReview) (
 	}
 
 	w.logger.WithCtxValues(ctx).Debugf("Webhook mutating review finished with: '%s' JSON Patch", string(res.JSONPatchPatch))
+	if w.cfg.PatchLogging {
+		w.logPatch(ctx, res)
+	}
+	w.tracer.SetValuesOnTrace(ctx, map[string]interface{}{"mutated": len(res.JSONPatchPatch) > 0})
 
 	return res, nil
 }
 
+// logPatch logs the JSON patch operations of the response, redacting the values of the
+// configured paths.
+func (w mutatingWebhook) logPatch(ctx context.Context, res *model.MutatingAdmissionResponse) {
+	logger := w.logger.WithCtxValues(ctx)
+
+	ops, err := PatchFromResponse(res)
+	if err != nil {
+		logger.Errorf("could not log JSON patch: %s", err)
+		return
+	}
+
+	for _, op := range ops {
+		kv := log.Kv{"patch-op": op.Operation, "patch-path": op.Path}
+		if op.Value != nil {
+			kv["patch-value"] = op.Value
+			if w.cfg.PatchLoggingRedactPath != nil && redactPatchValue(w.cfg.PatchLoggingRedactPath, op.Path, op.Value) {
+				kv["patch-value"] = "[REDACTED]"
+			}
+		}
+		logger.WithValues(kv).Infof("Mutation JSON patch operation")
+	}
+}
+
+// redactPatchValue checks if the JSON patch operation value should be redacted, this is when the operation
+// path or any of the value child paths match the redact regex (e.g `add /data` with `^/data/` will have
+// the value redacted because it sets `/data/xxx` paths).
+func redactPatchValue(redact *regexp.Regexp, path string, value interface{}) bool {
+	if redact.MatchString(path) {
+		return true
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, cv := range v {
+			if redactPatchValue(redact, path+"/"+jsonPointerTokenEscaper.Replace(k), cv) {
+				return true
+			}
+		}
+	case []interface{}:
+		for i, cv := range v {
+			if redactPatchValue(redact, path+"/"+strconv.Itoa(i), cv) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// decodeError returns the decode error or, if the decode errors are allowed, an allowed response
+// without mutation.
+func (w mutatingWebhook) decodeError(ctx context.Context, ar model.AdmissionReview, err error) (model.AdmissionResponse, error) {
+	if !w.cfg.AllowOnDecodeError {
+		return nil, err
+	}
+
+	w.logger.WithCtxValues(ctx).Warningf("Object decode failed, allowing without mutation: %s", err)
+	return &model.MutatingAdmissionResponse{ID: ar.ID}, nil
+}
+
+// decodeObjects will create the object for the mutation and the old object (if any) from the raw JSON data.
+func (w mutatingWebhook) decodeObjects(raw, oldRaw []byte) (obj metav1.Object, oldObj metav1.Object, err error) {
+	// Create a new object from the raw type.
+	runtimeObj, err := w.objectCreator.NewObject(raw)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create object from raw: %w", err)
+	}
+
+	obj, err = helpers.ToK8sObj(runtimeObj)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid object: %w", err)
+	}
+
+	if len(oldRaw) == 0 {
+		return obj, nil, nil
+	}
+
+	oldRuntimeObj, err := w.objectCreator.NewObject(oldRaw)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create old object from raw: %w", err)
+	}
+
+	oldObj, err = helpers.ToK8sObj(oldRuntimeObj)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid old object: %w", err)
+	}
+
+	return obj, oldObj, nil
+}
+
 func (w mutatingWebhook) mutatingAdmissionReview(ctx context.Context, ar model.AdmissionReview, rawObj []byte, objForMutation metav1.Object) (*model.MutatingAdmissionResponse, error) {
+	// Don't mutate if the review has been cancelled (e.g timeout).
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context done before mutating: %w", err)
+	}
+
 	// Mutate the object.
-	res, err := w.mutator.Mutate(ctx, &ar, objForMutation)
+	mctx, ctxAuditAnnotations := contextWithAuditAnnotations(ctx)
+	mctx = webhook.ContextWithReview(mctx, &ar)
+	mctx = w.tracer.NewTrace(mctx, "mutate")
+	res, err := w.mutate(mctx, &ar, objForMutation)
+	w.tracer.EndTrace(mctx, err)
 	if err != nil {
+		// Allow the mutators to fail without failing the admission review.
+		if errors.Is(err, ErrAllowOnError) {
+			w.logger.WithCtxValues(ctx).Warningf("Mutator failed, allowing without mutation: %s", err)
+			return &model.MutatingAdmissionResponse{ID: ar.ID, AuditAnnotations: ctxAuditAnnotations.get()}, nil
+		}
+
 		return nil, fmt.Errorf("could not mutate object: %w", err)
 	}
 
@@ -122,19 +304,27 @@ func (w mutatingWebhook) mutatingAdmissionReview(ctx context.Context, ar model.A
 		return nil, fmt.Errorf("result is required, mutator result is nil")
 	}
 
-	// If there is predefined jsonPathc, use that instead to TODO
-	if res.JsonPatch != nil {
-		jp, err := json.Marshal(res.JsonPatch)
+	// Set the audit annotations set by the mutators using the context, the result ones have priority.
+	res.AuditAnnotations = helpers.MergeAuditAnnotations(ctxAuditAnnotations.get(), res.AuditAnnotations)
+
+	// The mutator could have ignored the context, the apiserver will not wait for us.
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context done after mutating: %w", err)
+	}
+
+	// If the mutator didn't mutate the object, we don't need to compute the object patch, although
+	// we could have the mutator JSON patch operations.
+	if res.NoMutation {
+		patch, err := w.createOperationsJSONPatch(rawObj, res.JsonPatch)
 		if err != nil {
-			return nil, fmt.Errorf("could not marshal JSON patch: %w", err)
+			return nil, err
 		}
 
-		fmt.Println(string(jp))
-
 		return &model.MutatingAdmissionResponse{
-			ID:             ar.ID,
-			JSONPatchPatch: jp,
-			Warnings:       res.Warnings,
+			ID:               ar.ID,
+			JSONPatchPatch:   patch,
+			Warnings:         res.Warnings,
+			AuditAnnotations: res.AuditAnnotations,
 		}, nil
 	}
 
@@ -144,25 +334,262 @@ func (w mutatingWebhook) mutatingAdmissionReview(ctx context.Context, ar model.A
 	if res.MutatedObject != nil {
 		mutatedObj = res.MutatedObject
 	}
+
+	pctx := w.tracer.NewTrace(ctx, "patch")
+	patch, err := w.createJSONPatch(rawObj, mutatedObj, res.JsonPatch)
+	w.tracer.EndTrace(pctx, err)
+	if err != nil {
+		return nil, err
+	}
+
+	// Forge response.
+	return &model.MutatingAdmissionResponse{
+		ID:               ar.ID,
+		JSONPatchPatch:   patch,
+		Warnings:         res.Warnings,
+		AuditAnnotations: res.AuditAnnotations,
+	}, nil
+}
+
+// mutate executes the mutator, if enabled, recovering the mutator panics and returning them as errors, this way
+// the panics will be handled like any other mutator error (e.g fail open webhooks).
+func (w mutatingWebhook) mutate(ctx context.Context, ar *model.AdmissionReview, obj metav1.Object) (_ *MutatorResult, err error) {
+	if !w.cfg.DisablePanicRecovery {
+		defer func() {
+			if r := recover(); r != nil {
+				w.logger.WithCtxValues(ctx).Errorf("mutator panicked: %v\n%s", r, debug.Stack())
+				err = fmt.Errorf("mutator panicked: %v", r)
+			}
+		}()
+	}
+
+	return w.mutator.Mutate(ctx, ar, obj)
+}
+
+// createJSONPatch returns the JSON patch between the original raw object and the mutated object,
+// followed by the received JSON patch operations, if there aren't operations it will return a `nil` patch.
+//
+// The original raw object received on the request is used as the patch source instead of marshaling
+// the decoded object, this way we don't generate patch operations for fields changed by the decoding
+// (e.g ordering, defaults...) and we save one marshal. The integers are not converted to floats on the way
+// (typed objects have integer types and unstructured objects are decoded with int64 numbers), so unchanged
+// numeric fields (e.g `spec.replicas`) don't produce patch operations.
+//
+// The patch operations are in a deterministic order (the order of the object fields), they are not sorted
+// because the order of the operations matters (e.g array elements removal), so the same mutation will
+// always produce the same patch.
+//
+// Strategic merge patches are not supported by Kubernetes admission, as the patch is computed from the whole
+// mutated object, the list entries that are not mutated are kept (e.g `env` of containers). The list entries
+// are patched by their index (there are no list merge keys), so inserting an entry will patch the entries
+// shifted after it.
+func (w mutatingWebhook) createJSONPatch(rawObj []byte, mutatedObj metav1.Object, ops []JsonPatchOperation) ([]byte, error) {
 	mutatedJSON, err := json.Marshal(mutatedObj)
 	if err != nil {
 		return nil, fmt.Errorf("could not marshal into JSON mutated object: %w", err)
 	}
 
-	patch, err := jsonpatch.CreatePatch(rawObj, mutatedJSON)
+	objOps, err := w.cfg.PatchComputer.ComputePatch(rawObj, mutatedJSON)
 	if err != nil {
 		return nil, fmt.Errorf("could not create JSON patch: %w", err)
 	}
 
+	patch, err := resolveJSONPatchConflicts(rawObj, toJSONPatch(append(objOps, ops...)), w.cfg.PatchConflictLastWins)
+	if err != nil {
+		return nil, err
+	}
+
+	return marshalJSONPatch(patch)
+}
+
+// createOperationsJSONPatch returns the JSON patch of the received JSON patch operations, if there
+// aren't operations it will return a `nil` patch.
+func (w mutatingWebhook) createOperationsJSONPatch(rawObj []byte, ops []JsonPatchOperation) ([]byte, error) {
+	patch, err := resolveJSONPatchConflicts(rawObj, toJSONPatch(ops), w.cfg.PatchConflictLastWins)
+	if err != nil {
+		return nil, err
+	}
+
+	return marshalJSONPatch(patch)
+}
+
+// resolveJSONPatchConflicts checks the JSON patch has multiple `add` operations on the same path. These are
+// valid JSON patches (an `add` on an existing member replaces it), but the previous operations would be lost
+// silently, this happens when different mutations step on each other (e.g mutators of a chain returning JSON
+// patch operations). If last wins is enabled, the conflicts will be resolved using the last operation.
+//
+// Array insertions (e.g `/a/0` or `/a/-`) are not conflicts, these can be used multiple times. The array
+// indexes are checked against the object with the previous operations of the patch applied (e.g arrays
+// created on the same patch), so numeric map keys (e.g `/metadata/labels/1`) are not handled as array insertions.
+func resolveJSONPatchConflicts(rawObj []byte, patch []jsonpatch.Operation, lastWins bool) ([]jsonpatch.Operation, error) {
+	// If the object can't be unmarshaled, only the `-` token will be handled as an array insertion.
+	var obj interface{}
+	_ = json.Unmarshal(rawObj, &obj)
+
+	lastAdds := map[string]int{}
+	for i, op := range patch {
+		if op.Operation != "add" && op.Operation != "replace" {
+			continue
+		}
+
+		// Track the values set by the operations, so the next operations know the arrays created by them.
+		arrayPath := isJSONPatchArrayPath(obj, op.Path)
+		obj = setJSONPointerValue(obj, op.Path, op.Value, op.Operation == "add" && arrayPath)
+		if op.Operation != "add" || arrayPath {
+			continue
+		}
+
+		if _, ok := lastAdds[op.Path]; ok && !lastWins {
+			return nil, fmt.Errorf("JSON patch conflict, multiple add operations on %q path", op.Path)
+		}
+		lastAdds[op.Path] = i
+	}
+
+	// Nothing to resolve.
+	if len(lastAdds) == 0 || !lastWins {
+		return patch, nil
+	}
+
+	resolved := make([]jsonpatch.Operation, 0, len(patch))
+	for i, op := range patch {
+		if last, ok := lastAdds[op.Path]; ok && op.Operation == "add" && last != i {
+			continue
+		}
+		resolved = append(resolved, op)
+	}
+
+	return resolved, nil
+}
+
+// isJSONPatchArrayPath checks if the JSON patch path is an array element, the last path token is `-` or
+// an index and the parent is an array on the object.
+func isJSONPatchArrayPath(obj interface{}, path string) bool {
+	i := strings.LastIndex(path, "/")
+	token := path[i+1:]
+	if token == "-" {
+		return true
+	}
+
+	if _, err := strconv.Atoi(token); err != nil {
+		return false
+	}
+
+	_, ok := jsonPointerValue(obj, path[:i]).([]interface{})
+	return ok
+}
+
+// jsonPointerValue returns the value of the JSON pointer path on the unmarshaled JSON object, if
+// the path is missing it will return `nil`.
+func jsonPointerValue(obj interface{}, path string) interface{} {
+	if path == "" {
+		return obj
+	}
+
+	for _, token := range strings.Split(strings.TrimPrefix(path, "/"), "/") {
+		token = jsonPointerTokenUnescaper.Replace(token)
+		switch v := obj.(type) {
+		case map[string]interface{}:
+			obj = v[token]
+		case []interface{}:
+			idx, err := strconv.Atoi(token)
+			if err != nil || idx < 0 || idx >= len(v) {
+				return nil
+			}
+			obj = v[idx]
+		default:
+			return nil
+		}
+	}
+
+	return obj
+}
+
+// setJSONPointerValue sets the value on the JSON pointer path of the unmarshaled JSON object, if insert is
+// enabled the value will be inserted on the parent array instead of replacing the element. The paths with
+// missing parents will be ignored. It returns the object with the value set.
+func setJSONPointerValue(obj interface{}, path string, value interface{}, insert bool) interface{} {
+	if path == "" {
+		return toJSONValue(value)
+	}
+
+	i := strings.LastIndex(path, "/")
+	token := jsonPointerTokenUnescaper.Replace(path[i+1:])
+	switch parent := jsonPointerValue(obj, path[:i]).(type) {
+	case map[string]interface{}:
+		parent[token] = toJSONValue(value)
+	case []interface{}:
+		idx := len(parent)
+		if token != "-" {
+			n, err := strconv.Atoi(token)
+			if err != nil || n < 0 || n > len(parent) {
+				return obj
+			}
+			idx = n
+		}
+
+		switch {
+		case insert:
+			parent = append(parent[:idx], append([]interface{}{toJSONValue(value)}, parent[idx:]...)...)
+		case idx < len(parent):
+			parent[idx] = toJSONValue(value)
+		default:
+			return obj
+		}
+
+		// The array could have grown, set it again on its parent.
+		return setJSONPointerValue(obj, path[:i], parent, false)
+	}
+
+	return obj
+}
+
+// toJSONValue returns the value as an unmarshaled JSON value (e.g structs or typed slices as
+// maps and slices of interfaces), if the value can't be converted it will return `nil`.
+func toJSONValue(value interface{}) interface{} {
+	switch value.(type) {
+	case nil, string, bool, float64:
+		return value
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil
+	}
+
+	return v
+}
+
+var (
+	jsonPointerTokenEscaper   = strings.NewReplacer("~", "~0", "/", "~1")
+	jsonPointerTokenUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+)
+
+// toJSONPatch converts the operations to the JSON patch library operations.
+func toJSONPatch(ops []JsonPatchOperation) []jsonpatch.Operation {
+	patch := make([]jsonpatch.Operation, 0, len(ops))
+	for _, op := range ops {
+		patch = append(patch, jsonpatch.NewOperation(op.Operation, op.Path, op.Value))
+	}
+
+	return patch
+}
+
+// marshalJSONPatch marshals the JSON patch, empty patches will be returned as `nil`.
+func marshalJSONPatch(patch []jsonpatch.Operation) ([]byte, error) {
+	// Don't return empty patches.
+	if len(patch) == 0 {
+		return nil, nil
+	}
+
 	marshalledPatch, err := json.Marshal(patch)
 	if err != nil {
 		return nil, fmt.Errorf("could not mashal into JSON, the JSON patch: %w", err)
 	}
 
-	// Forge response.
-	return &model.MutatingAdmissionResponse{
-		ID:             ar.ID,
-		JSONPatchPatch: marshalledPatch,
-		Warnings:       res.Warnings,
-	}, nil
+	return marshalledPatch, nil
 }
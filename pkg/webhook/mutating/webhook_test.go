@@ -3,19 +3,38 @@ package mutating_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"regexp"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 
+	"github.com/slok/kubewebhook/v2/pkg/log"
 	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/webhook"
 	"github.com/slok/kubewebhook/v2/pkg/webhook/mutating"
 )
 
+// getCustomScheme returns a scheme with a custom type registered (reusing a
+// known type as the Go struct of the custom kind).
+func getCustomScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	scheme.AddKnownTypeWithName(schema.GroupVersionKind{Group: "example.io", Version: "v1", Kind: "Foo"}, &corev1.ConfigMap{})
+	return scheme
+}
+
 func getPodJSON() []byte {
 	pod := &corev1.Pod{
 		TypeMeta: metav1.TypeMeta{
@@ -136,6 +155,100 @@ func TestPodAdmissionReviewMutation(t *testing.T) {
 			expErr: true,
 		},
 
+		"A webhook review with a mutator that panics should return an error.": {
+			cfg: mutating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}},
+			mutator: mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+				var labels map[string]string
+				labels["panic"] = "true"
+				return &mutating.MutatorResult{}, nil
+			}),
+			review: model.AdmissionReview{
+				ID:           "test",
+				NewObjectRaw: getPodJSON(),
+			},
+			expErr: true,
+		},
+
+		"A mutator should have the webhook ID on the context.": {
+			cfg: mutating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}},
+			mutator: mutating.MutatorFunc(func(ctx context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+				obj.SetNamespace(webhook.IDFromContext(ctx))
+				return &mutating.MutatorResult{MutatedObject: obj}, nil
+			}),
+			review: model.AdmissionReview{
+				ID:           "test",
+				NewObjectRaw: getPodJSON(),
+			},
+			expPatch: []string{
+				`{"op":"replace","path":"/metadata/namespace","value":"test"}`,
+			},
+		},
+
+		"A mutator should have the admission review on the context.": {
+			cfg: mutating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}},
+			mutator: mutating.MutatorFunc(func(ctx context.Context, ar *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+				ctxAR := webhook.ReviewFromContext(ctx)
+				if ctxAR != ar {
+					return nil, fmt.Errorf("context review is not the mutator review")
+				}
+				obj.SetNamespace(ctxAR.Namespace + "-" + string(ctxAR.Operation))
+				return &mutating.MutatorResult{MutatedObject: obj}, nil
+			}),
+			review: model.AdmissionReview{
+				ID:           "test",
+				Namespace:    "testNS",
+				Operation:    model.OperationCreate,
+				NewObjectRaw: getPodJSON(),
+			},
+			expPatch: []string{
+				`{"op":"replace","path":"/metadata/namespace","value":"testNS-create"}`,
+			},
+		},
+
+		"A mutator should have the user info of the review on the context.": {
+			cfg: mutating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}},
+			mutator: mutating.MutatorFunc(func(ctx context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+				u := mutating.UserInfoFromContext(ctx)
+				obj.SetNamespace(fmt.Sprintf("%s %s %v %v", u.Username, u.UID, u.Groups, u.Extra))
+				return &mutating.MutatorResult{MutatedObject: obj}, nil
+			}),
+			review: model.AdmissionReview{
+				ID:           "test",
+				NewObjectRaw: getPodJSON(),
+				UserInfo: authenticationv1.UserInfo{
+					Username: "user1",
+					UID:      "1234",
+					Groups:   []string{"group1", "group2"},
+					Extra:    map[string]authenticationv1.ExtraValue{"k1": {"v1"}},
+				},
+			},
+			expPatch: []string{
+				`{"op":"replace","path":"/metadata/namespace","value":"user1 1234 [group1 group2] map[k1:[v1]]"}`,
+			},
+		},
+
+		"A static webhook review of a Pod with a mutator returning a new object should mutate using the new object.": {
+			cfg: mutating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}},
+			mutator: mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+				newPod := &corev1.Pod{
+					TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+					ObjectMeta: metav1.ObjectMeta{Name: obj.GetName(), Namespace: obj.GetNamespace()},
+					Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "container1"}}},
+				}
+				return &mutating.MutatorResult{MutatedObject: newPod}, nil
+			}),
+			review: model.AdmissionReview{
+				ID:           "test",
+				NewObjectRaw: getPodJSON(),
+			},
+			expPatch: []string{
+				`{"op":"remove","path":"/metadata/annotations"}`,
+				`{"op":"remove","path":"/spec/containers/0/resources/limits"}`,
+				`{"op":"remove","path":"/spec/containers/0/resources/requests"}`,
+				`{"op":"remove","path":"/spec/containers/1"}`,
+			},
+		},
+
 		"A static webhook review of a Pod with an ns mutator should mutate the ns.": {
 			cfg: mutating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}},
 			mutator: mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
@@ -202,6 +315,36 @@ func TestPodAdmissionReviewMutation(t *testing.T) {
 			},
 		},
 
+		"A static webhook review of a Pod with a mutator that returns JSON patch operations should add them after the object mutation.": {
+			cfg: mutating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}},
+			mutator: mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+				obj.SetNamespace("myChangedNS")
+				return &mutating.MutatorResult{
+					MutatedObject: obj,
+					JsonPatch: []mutating.JsonPatchOperation{
+						{Operation: "add", Path: "/metadata/labels", Value: map[string]string{"team": "team1"}},
+					},
+				}, nil
+			}),
+			review: model.AdmissionReview{
+				ID:           "test",
+				NewObjectRaw: getPodJSON(),
+			},
+			expPatch: []string{
+				`[{"op":"replace","path":"/metadata/namespace","value":"myChangedNS"},{"op":"add","path":"/metadata/labels","value":{"team":"team1"}}]`,
+			},
+		},
+
+		"A static webhook review of an object with a different type of the webhook should fail.": {
+			cfg:     mutating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}},
+			mutator: getPodNSMutator("myChangedNS"),
+			review: model.AdmissionReview{
+				ID:           "test",
+				NewObjectRaw: []byte(`{"kind":"Service","apiVersion":"v1","metadata":{"name":"testSvc","namespace":"myNS"}}`),
+			},
+			expErr: true,
+		},
+
 		"A static webhook review of delete operation in a Pod should mutate the pod correctly.": {
 			cfg:     mutating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}},
 			mutator: getPodResourceLimitDeletorMutator(),
@@ -216,6 +359,67 @@ func TestPodAdmissionReviewMutation(t *testing.T) {
 			},
 		},
 
+		"A static webhook review of an update operation should have the old object available to the mutator.": {
+			cfg: mutating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}},
+			mutator: mutating.MutatorFunc(func(ctx context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+				oldPod, ok := mutating.OldObjectFromContext(ctx).(*corev1.Pod)
+				if !ok {
+					return nil, fmt.Errorf("old object is not a pod")
+				}
+
+				pod := obj.(*corev1.Pod)
+				pod.Annotations["old-name"] = oldPod.Name
+
+				return &mutating.MutatorResult{MutatedObject: pod}, nil
+			}),
+			review: model.AdmissionReview{
+				Operation:    model.OperationUpdate,
+				ID:           "test",
+				OldObjectRaw: []byte(`{"kind":"Pod","apiVersion":"v1","metadata":{"name":"oldTestPod"}}`),
+				NewObjectRaw: getPodJSON(),
+			},
+			expPatch: []string{
+				`{"op":"add","path":"/metadata/annotations/old-name","value":"oldTestPod"}`,
+			},
+		},
+
+		"A static webhook review of a create operation should not have the old object available to the mutator.": {
+			cfg: mutating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}},
+			mutator: mutating.MutatorFunc(func(ctx context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+				if mutating.OldObjectFromContext(ctx) != nil {
+					return nil, fmt.Errorf("old object should be missing")
+				}
+
+				return &mutating.MutatorResult{}, nil
+			}),
+			review: model.AdmissionReview{
+				Operation:    model.OperationCreate,
+				ID:           "test",
+				NewObjectRaw: getPodJSON(),
+			},
+			expPatch: []string{},
+		},
+
+		"A static webhook review of a Pod with a mutator chain should have a patch with all the chain mutations.": {
+			cfg: mutating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}},
+			mutator: mutating.NewChain(log.Noop,
+				getPodNSMutator("myChangedNS"),
+				getPodAnnotationsReplacerMutator(map[string]string{"key1": "val1_mutated"}),
+				getPodResourceLimitDeletorMutator(),
+			),
+			review: model.AdmissionReview{
+				ID:           "test",
+				NewObjectRaw: getPodJSON(),
+			},
+			expPatch: []string{
+				`{"op":"replace","path":"/metadata/namespace","value":"myChangedNS"}`,
+				`{"op":"replace","path":"/metadata/annotations/key1","value":"val1_mutated"}`,
+				`{"op":"remove","path":"/metadata/annotations/key2"}`,
+				`{"op":"remove","path":"/spec/containers/0/resources/limits"}`,
+				`{"op":"remove","path":"/spec/containers/1/resources/limits"}`,
+			},
+		},
+
 		"A dynamic webhook review of a Pod with an ns mutator should mutate the ns.": {
 			cfg:     mutating.WebhookConfig{ID: "test"},
 			mutator: getPodNSMutator("myChangedNS"),
@@ -260,6 +464,45 @@ func TestPodAdmissionReviewMutation(t *testing.T) {
 			},
 		},
 
+		"A dynamic webhook review with a custom scheme should be able to mutate custom types (e.g CRDs) with their typed objects.": {
+			cfg: mutating.WebhookConfig{ID: "test", Scheme: getCustomScheme()},
+			mutator: mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+				cm, ok := obj.(*corev1.ConfigMap)
+				if !ok {
+					return nil, fmt.Errorf("not a typed custom object")
+				}
+
+				cm.Data["key1"] = "mutated-value1"
+				return &mutating.MutatorResult{MutatedObject: cm}, nil
+			}),
+			review: model.AdmissionReview{
+				ID:           "test",
+				NewObjectRaw: []byte(`{"kind":"Foo","apiVersion":"example.io/v1","metadata":{"name":"something","namespace":"someplace"},"data":{"key1":"value1"}}`),
+			},
+			expPatch: []string{
+				`{"op":"replace","path":"/data/key1","value":"mutated-value1"}`,
+			},
+		},
+
+		"A dynamic webhook review with a custom scheme of a type not registered on the scheme should fallback to unstructured.": {
+			cfg: mutating.WebhookConfig{ID: "test", Scheme: getCustomScheme()},
+			mutator: mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+				if _, ok := obj.(runtime.Unstructured); !ok {
+					return nil, fmt.Errorf("not unstructured")
+				}
+
+				obj.SetLabels(map[string]string{"injected": "true"})
+				return &mutating.MutatorResult{MutatedObject: obj}, nil
+			}),
+			review: model.AdmissionReview{
+				ID:           "test",
+				NewObjectRaw: getPodJSON(),
+			},
+			expPatch: []string{
+				`{"op":"add","path":"/metadata/labels","value":{"injected":"true"}}`,
+			},
+		},
+
 		"A dynamic webhook review of a an unknown type should be able to mutate with the common object attributes (check unstructured object mutation).": {
 			cfg: mutating.WebhookConfig{ID: "test"},
 			mutator: mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
@@ -382,3 +625,983 @@ func TestPodAdmissionReviewMutation(t *testing.T) {
 		})
 	}
 }
+
+func TestPodAdmissionReviewDeny(t *testing.T) {
+	tests := map[string]struct {
+		mutator   mutating.Mutator
+		expStatus *webhook.StatusError
+	}{
+		"A mutator returning a status error should deny with the status.": {
+			mutator: mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+				return nil, webhook.NewStatusError(http.StatusForbidden, metav1.StatusReasonForbidden, "immutable field")
+			}),
+			expStatus: webhook.NewStatusError(http.StatusForbidden, metav1.StatusReasonForbidden, "immutable field"),
+		},
+
+		"A mutator returning a wrapped status error should deny with the status.": {
+			mutator: mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+				return nil, fmt.Errorf("could not inject: %w", webhook.NewStatusError(http.StatusForbidden, metav1.StatusReasonForbidden, "immutable field"))
+			}),
+			expStatus: webhook.NewStatusError(http.StatusForbidden, metav1.StatusReasonForbidden, "immutable field"),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			wh, err := mutating.NewWebhook(mutating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}, Mutator: test.mutator})
+			assert.NoError(err)
+
+			_, err = wh.Review(context.TODO(), model.AdmissionReview{ID: "test", NewObjectRaw: getPodJSON()})
+
+			var gotStatus *webhook.StatusError
+			if assert.True(errors.As(err, &gotStatus)) {
+				assert.Equal(test.expStatus, gotStatus)
+			}
+		})
+	}
+}
+
+func TestPodAdmissionReviewNoMutation(t *testing.T) {
+	tests := map[string]struct {
+		mutator mutating.Mutator
+	}{
+		"A mutator that doesn't change the object should not return a patch.": {
+			mutator: mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+				return &mutating.MutatorResult{MutatedObject: obj}, nil
+			}),
+		},
+
+		"A mutator that fails with an allow on error should not return a patch.": {
+			mutator: mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+				obj.SetNamespace("myChangedNS")
+				return nil, fmt.Errorf("optional mutation failed: %w", mutating.ErrAllowOnError)
+			}),
+		},
+
+		"A mutator that says it didn't mutate the object should not return a patch.": {
+			mutator: mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+				// This mutation should be ignored.
+				obj.SetNamespace("myChangedNS")
+				return &mutating.MutatorResult{NoMutation: true, Warnings: []string{"warn1"}}, nil
+			}),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			wh, err := mutating.NewWebhook(mutating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}, Mutator: test.mutator})
+			assert.NoError(err)
+
+			gotResponse, err := wh.Review(context.TODO(), model.AdmissionReview{ID: "test", NewObjectRaw: getPodJSON()})
+			if assert.NoError(err) {
+				got := gotResponse.(*model.MutatingAdmissionResponse)
+				assert.Empty(got.JSONPatchPatch)
+			}
+		})
+	}
+}
+
+func TestCustomResourceAdmissionReviewPatch(t *testing.T) {
+	// The raw object has unordered keys, nested unknown fields and big numbers
+	// that should be left untouched by the patch.
+	crJSON := []byte(`
+		{
+			"spec": {"replicas": 3, "big": 9007199254740993, "nested": {"b": [1, 2], "a": null}},
+			"metadata": {"namespace": "someplace", "name": "something", "labels": {"test1": "value1"}},
+			"apiVersion": "example.io/v1",
+			"kind": "Foo",
+			"status": {"ready": true}
+		}`)
+
+	tests := map[string]struct {
+		mutator  mutating.Mutator
+		expPatch string
+	}{
+		"Mutating only a label on a custom resource should return a single operation patch.": {
+			mutator: mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+				obj.SetLabels(map[string]string{"test1": "mutated-value1"})
+				return &mutating.MutatorResult{MutatedObject: obj}, nil
+			}),
+			expPatch: `[{"op":"replace","path":"/metadata/labels/test1","value":"mutated-value1"}]`,
+		},
+
+		"Mutating multiple fields on a custom resource should return the patch operations in a stable order.": {
+			mutator: mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+				obj.SetLabels(map[string]string{"test3": "value3", "test1": "value1", "test2": "value2", "test0": "value0"})
+				obj.SetAnnotations(map[string]string{"b": "2", "a": "1", "c": "3"})
+				return &mutating.MutatorResult{MutatedObject: obj}, nil
+			}),
+			expPatch: `[{"op":"add","path":"/metadata/annotations","value":{"a":"1","b":"2","c":"3"}},{"op":"add","path":"/metadata/labels/test0","value":"value0"},{"op":"add","path":"/metadata/labels/test2","value":"value2"},{"op":"add","path":"/metadata/labels/test3","value":"value3"}]`,
+		},
+
+		"Removing multiple array elements on a custom resource should return the patch operations in a stable order that can be applied.": {
+			mutator: mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+				spec := obj.(runtime.Unstructured).UnstructuredContent()["spec"].(map[string]interface{})
+				spec["nested"].(map[string]interface{})["b"] = []interface{}{}
+				obj.SetLabels(map[string]string{"test0": "value0"})
+				return &mutating.MutatorResult{MutatedObject: obj}, nil
+			}),
+			// Array removals are in reverse order, sorting the operations by path would make the patch invalid.
+			expPatch: `[{"op":"add","path":"/metadata/labels/test0","value":"value0"},{"op":"remove","path":"/metadata/labels/test1"},{"op":"remove","path":"/spec/nested/b/1"},{"op":"remove","path":"/spec/nested/b/0"}]`,
+		},
+
+		"Not mutating a custom resource should not return a patch.": {
+			mutator: mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+				return &mutating.MutatorResult{MutatedObject: obj}, nil
+			}),
+			expPatch: ``,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			wh, err := mutating.NewWebhook(mutating.WebhookConfig{ID: "test", Mutator: test.mutator})
+			assert.NoError(err)
+
+			// Review multiple times, the patches should be byte-identical between reviews.
+			for i := 0; i < 5; i++ {
+				gotResponse, err := wh.Review(context.TODO(), model.AdmissionReview{ID: "test", NewObjectRaw: crJSON})
+				if assert.NoError(err) {
+					got := gotResponse.(*model.MutatingAdmissionResponse)
+					assert.Equal(test.expPatch, string(got.JSONPatchPatch))
+				}
+			}
+		})
+	}
+}
+
+func TestPodAdmissionReviewPatchListEntries(t *testing.T) {
+	// Kubernetes admission only supports JSON patches, the patch is computed from the whole mutated object
+	// so the list entries (e.g containers and env vars) not mutated are kept. The list entries are patched
+	// by index (there are no merge keys), so inserting an entry patches the entries after it.
+	podJSON := []byte(`{"kind":"Pod","apiVersion":"v1","metadata":{"name":"test","namespace":"test","creationTimestamp":null},"spec":{"containers":[{"name":"app","image":"app","env":[{"name":"A","value":"a"},{"name":"B","value":"b"}],"resources":{}},{"name":"sidecar","image":"sidecar","env":[{"name":"S","value":"s"}],"resources":{}}]},"status":{}}`)
+
+	tests := map[string]struct {
+		obj      metav1.Object
+		mutate   func(containers []corev1.Container)
+		expPatch string
+	}{
+		"Injecting an env var on a static webhook should not replace the other env vars and containers.": {
+			obj: &corev1.Pod{},
+			mutate: func(containers []corev1.Container) {
+				containers[0].Env = append(containers[0].Env, corev1.EnvVar{Name: "C", Value: "c"})
+			},
+			expPatch: `[{"op":"add","path":"/spec/containers/0/env/2","value":{"name":"C","value":"c"}}]`,
+		},
+
+		"Injecting an env var on a dynamic webhook should not replace the other env vars and containers.": {
+			obj: nil,
+			mutate: func(containers []corev1.Container) {
+				containers[0].Env = append(containers[0].Env, corev1.EnvVar{Name: "C", Value: "c"})
+			},
+			expPatch: `[{"op":"add","path":"/spec/containers/0/env/2","value":{"name":"C","value":"c"}}]`,
+		},
+
+		"Inserting an env var at the start should patch the env vars by their index.": {
+			obj: &corev1.Pod{},
+			mutate: func(containers []corev1.Container) {
+				containers[0].Env = append([]corev1.EnvVar{{Name: "C", Value: "c"}}, containers[0].Env...)
+			},
+			expPatch: `[{"op":"add","path":"/spec/containers/0/env/2","value":{"name":"B","value":"b"}},{"op":"replace","path":"/spec/containers/0/env/0/name","value":"C"},{"op":"replace","path":"/spec/containers/0/env/0/value","value":"c"},{"op":"replace","path":"/spec/containers/0/env/1/name","value":"A"},{"op":"replace","path":"/spec/containers/0/env/1/value","value":"a"}]`,
+		},
+
+		"Changing an env var value should only patch the env var value.": {
+			obj: &corev1.Pod{},
+			mutate: func(containers []corev1.Container) {
+				containers[1].Env[0].Value = "mutated"
+			},
+			expPatch: `[{"op":"replace","path":"/spec/containers/1/env/0/value","value":"mutated"}]`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mutator := mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+				var pod *corev1.Pod
+				if err := webhook.AsObject(obj, &pod); err != nil {
+					return nil, err
+				}
+
+				test.mutate(pod.Spec.Containers)
+
+				return &mutating.MutatorResult{MutatedObject: pod}, nil
+			})
+			wh, err := mutating.NewWebhook(mutating.WebhookConfig{ID: "test", Obj: test.obj, Mutator: mutator})
+			require.NoError(err)
+
+			gotResponse, err := wh.Review(context.TODO(), model.AdmissionReview{ID: "test", NewObjectRaw: podJSON})
+			if assert.NoError(err) {
+				got := gotResponse.(*model.MutatingAdmissionResponse)
+				assert.Equal(test.expPatch, string(got.JSONPatchPatch))
+			}
+		})
+	}
+}
+
+func TestDeploymentAdmissionReviewPatchNumbers(t *testing.T) {
+	// The Deployment has all the fields the typed object marshals, so the only changes are the mutated ones.
+	deployJSON := []byte(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"test","namespace":"test","creationTimestamp":null,"labels":{"test1":"value1"}},"spec":{"replicas":3,"revisionHistoryLimit":10,"progressDeadlineSeconds":600,"strategy":{},"selector":{"matchLabels":{"app":"test"}},"template":{"metadata":{"creationTimestamp":null,"labels":{"app":"test"}},"spec":{"terminationGracePeriodSeconds":30,"containers":[{"name":"test","image":"test","resources":{"limits":{"cpu":"500m","memory":"128Mi"}}}]}}},"status":{}}`)
+	labelMutator := mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+		obj.SetLabels(map[string]string{"test1": "mutated-value1"})
+		return &mutating.MutatorResult{MutatedObject: obj}, nil
+	})
+
+	tests := map[string]struct {
+		obj metav1.Object
+	}{
+		"Mutating a label on a static webhook should not patch the numeric fields.": {
+			obj: &appsv1.Deployment{},
+		},
+
+		"Mutating a label on a dynamic webhook should not patch the numeric fields.": {
+			obj: nil,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			wh, err := mutating.NewWebhook(mutating.WebhookConfig{ID: "test", Obj: test.obj, Mutator: labelMutator})
+			assert.NoError(err)
+
+			gotResponse, err := wh.Review(context.TODO(), model.AdmissionReview{ID: "test", NewObjectRaw: deployJSON})
+			if assert.NoError(err) {
+				got := gotResponse.(*model.MutatingAdmissionResponse)
+				assert.Equal(`[{"op":"replace","path":"/metadata/labels/test1","value":"mutated-value1"}]`, string(got.JSONPatchPatch))
+			}
+		})
+	}
+}
+
+func TestDefaultedPodAdmissionReviewPatch(t *testing.T) {
+	// Pod as received from the apiserver on a create, with the defaulted fields.
+	podJSON := []byte(`{"kind":"Pod","apiVersion":"v1","metadata":{"name":"test","namespace":"test","creationTimestamp":null,"labels":{"app":"test"}},"spec":{"volumes":[{"name":"default-token-x7bqc","secret":{"secretName":"default-token-x7bqc","defaultMode":420}}],"containers":[{"name":"test","image":"nginx","resources":{},"volumeMounts":[{"name":"default-token-x7bqc","readOnly":true,"mountPath":"/var/run/secrets/kubernetes.io/serviceaccount"}],"terminationMessagePath":"/dev/termination-log","terminationMessagePolicy":"File","imagePullPolicy":"Always"}],"restartPolicy":"Always","terminationGracePeriodSeconds":30,"dnsPolicy":"ClusterFirst","serviceAccountName":"default","serviceAccount":"default","securityContext":{},"schedulerName":"default-scheduler","tolerations":[{"key":"node.kubernetes.io/not-ready","operator":"Exists","effect":"NoExecute","tolerationSeconds":300},{"key":"node.kubernetes.io/unreachable","operator":"Exists","effect":"NoExecute","tolerationSeconds":300}],"priority":0,"enableServiceLinks":true,"preemptionPolicy":"PreemptLowerPriority"},"status":{"phase":"Pending","qosClass":"BestEffort"}}`)
+
+	tests := map[string]struct {
+		obj metav1.Object
+	}{
+		"Mutating a label on a static webhook should not patch the defaulted fields.": {
+			obj: &corev1.Pod{},
+		},
+
+		"Mutating a label on a dynamic webhook should not patch the defaulted fields.": {
+			obj: nil,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			mutator := mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+				obj.SetLabels(map[string]string{"app": "test", "mutated": "true"})
+				return &mutating.MutatorResult{MutatedObject: obj}, nil
+			})
+			wh, err := mutating.NewWebhook(mutating.WebhookConfig{ID: "test", Obj: test.obj, Mutator: mutator})
+			assert.NoError(err)
+
+			gotResponse, err := wh.Review(context.TODO(), model.AdmissionReview{ID: "test", NewObjectRaw: podJSON})
+			if assert.NoError(err) {
+				got := gotResponse.(*model.MutatingAdmissionResponse)
+				assert.Equal(`[{"op":"add","path":"/metadata/labels/mutated","value":"true"}]`, string(got.JSONPatchPatch))
+			}
+		})
+	}
+}
+
+func TestAdmissionReviewPatchConflicts(t *testing.T) {
+	getJSONPatchMutator := func(ops ...mutating.JsonPatchOperation) mutating.Mutator {
+		return mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+			return &mutating.MutatorResult{JsonPatch: ops}, nil
+		})
+	}
+
+	tests := map[string]struct {
+		lastWins bool
+		mutator  mutating.Mutator
+		expPatch string
+		expErr   bool
+	}{
+		"Multiple add operations on the same path should fail.": {
+			mutator: mutating.NewChain(log.Noop,
+				getJSONPatchMutator(mutating.JsonPatchOperation{Operation: "add", Path: "/metadata/labels", Value: map[string]string{"k1": "v1"}}),
+				getJSONPatchMutator(mutating.JsonPatchOperation{Operation: "add", Path: "/metadata/labels", Value: map[string]string{"k2": "v2"}}),
+			),
+			expErr: true,
+		},
+
+		"Multiple add operations on the same path with last wins should use the last one.": {
+			lastWins: true,
+			mutator: mutating.NewChain(log.Noop,
+				getJSONPatchMutator(mutating.JsonPatchOperation{Operation: "add", Path: "/metadata/labels", Value: map[string]string{"k1": "v1"}}),
+				getJSONPatchMutator(mutating.JsonPatchOperation{Operation: "add", Path: "/metadata/annotations", Value: map[string]string{"k1": "v1"}}),
+				getJSONPatchMutator(mutating.JsonPatchOperation{Operation: "add", Path: "/metadata/labels", Value: map[string]string{"k2": "v2"}}),
+			),
+			expPatch: `[{"op":"add","path":"/metadata/annotations","value":{"k1":"v1"}},{"op":"add","path":"/metadata/labels","value":{"k2":"v2"}}]`,
+		},
+
+		"Multiple add operations on the same array should not be a conflict.": {
+			mutator: mutating.NewChain(log.Noop,
+				getJSONPatchMutator(mutating.JsonPatchOperation{Operation: "add", Path: "/spec/items/-", Value: "a"}),
+				getJSONPatchMutator(mutating.JsonPatchOperation{Operation: "add", Path: "/spec/items/-", Value: "b"}),
+				getJSONPatchMutator(mutating.JsonPatchOperation{Operation: "add", Path: "/spec/items/0", Value: "c"}),
+				getJSONPatchMutator(mutating.JsonPatchOperation{Operation: "add", Path: "/spec/items/0", Value: "d"}),
+			),
+			expPatch: `[{"op":"add","path":"/spec/items/-","value":"a"},{"op":"add","path":"/spec/items/-","value":"b"},{"op":"add","path":"/spec/items/0","value":"c"},{"op":"add","path":"/spec/items/0","value":"d"}]`,
+		},
+
+		"Multiple add operations on an array created on the same patch should not be a conflict.": {
+			mutator: mutating.NewChain(log.Noop,
+				getJSONPatchMutator(mutating.JsonPatchOperation{Operation: "add", Path: "/spec/list", Value: []string{}}),
+				getJSONPatchMutator(mutating.JsonPatchOperation{Operation: "add", Path: "/spec/list/0", Value: "a"}),
+				getJSONPatchMutator(mutating.JsonPatchOperation{Operation: "add", Path: "/spec/list/0", Value: "b"}),
+			),
+			expPatch: `[{"op":"add","path":"/spec/list","value":[]},{"op":"add","path":"/spec/list/0","value":"a"},{"op":"add","path":"/spec/list/0","value":"b"}]`,
+		},
+
+		"Multiple add operations on a nested array created on the same patch should not be a conflict.": {
+			mutator: mutating.NewChain(log.Noop,
+				getJSONPatchMutator(mutating.JsonPatchOperation{Operation: "add", Path: "/spec/nested", Value: map[string][]string{"list": {"z"}}}),
+				getJSONPatchMutator(mutating.JsonPatchOperation{Operation: "add", Path: "/spec/nested/list/1", Value: "a"}),
+				getJSONPatchMutator(mutating.JsonPatchOperation{Operation: "add", Path: "/spec/nested/list/1", Value: "b"}),
+			),
+			expPatch: `[{"op":"add","path":"/spec/nested","value":{"list":["z"]}},{"op":"add","path":"/spec/nested/list/1","value":"a"},{"op":"add","path":"/spec/nested/list/1","value":"b"}]`,
+		},
+
+		"Multiple add operations on the same numeric key of a map created on the same patch should fail.": {
+			mutator: mutating.NewChain(log.Noop,
+				getJSONPatchMutator(mutating.JsonPatchOperation{Operation: "add", Path: "/spec/map", Value: map[string]string{}}),
+				getJSONPatchMutator(mutating.JsonPatchOperation{Operation: "add", Path: "/spec/map/0", Value: "a"}),
+				getJSONPatchMutator(mutating.JsonPatchOperation{Operation: "add", Path: "/spec/map/0", Value: "b"}),
+			),
+			expErr: true,
+		},
+
+		"Multiple add operations on the same numeric map key should fail.": {
+			mutator: mutating.NewChain(log.Noop,
+				getJSONPatchMutator(mutating.JsonPatchOperation{Operation: "add", Path: "/metadata/labels/1", Value: "a"}),
+				getJSONPatchMutator(mutating.JsonPatchOperation{Operation: "add", Path: "/metadata/labels/1", Value: "b"}),
+			),
+			expErr: true,
+		},
+
+		"Multiple add operations on the same numeric map key with last wins should use the last one.": {
+			lastWins: true,
+			mutator: mutating.NewChain(log.Noop,
+				getJSONPatchMutator(mutating.JsonPatchOperation{Operation: "add", Path: "/metadata/labels/1", Value: "a"}),
+				getJSONPatchMutator(mutating.JsonPatchOperation{Operation: "add", Path: "/metadata/labels/1", Value: "b"}),
+			),
+			expPatch: `[{"op":"add","path":"/metadata/labels/1","value":"b"}]`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			wh, err := mutating.NewWebhook(mutating.WebhookConfig{ID: "test", Mutator: test.mutator, PatchConflictLastWins: test.lastWins})
+			assert.NoError(err)
+
+			obj := []byte(`{"kind":"Foo","apiVersion":"example.io/v1","metadata":{"name":"test","labels":{"0":"z"}},"spec":{"items":["z"]}}`)
+			gotResponse, err := wh.Review(context.TODO(), model.AdmissionReview{ID: "test", NewObjectRaw: obj})
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				got := gotResponse.(*model.MutatingAdmissionResponse)
+				assert.Equal(test.expPatch, string(got.JSONPatchPatch))
+			}
+		})
+	}
+}
+
+func TestAdmissionReviewPatchComputer(t *testing.T) {
+	labelMutator := mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+		obj.SetLabels(map[string]string{"test1": "mutated-value1"})
+		return &mutating.MutatorResult{
+			MutatedObject: obj,
+			JsonPatch:     []mutating.JsonPatchOperation{{Operation: "add", Path: "/spec/replicas", Value: 5}},
+		}, nil
+	})
+
+	tests := map[string]struct {
+		mutator       mutating.Mutator
+		patchComputer mutating.PatchComputer
+		expPatch      string
+		expErr        bool
+	}{
+		"By default the patch should be computed with the default patch computer.": {
+			expPatch: `[{"op":"replace","path":"/metadata/labels/test1","value":"mutated-value1"},{"op":"add","path":"/spec/replicas","value":5}]`,
+		},
+
+		"Having a custom patch computer, the patch should be computed with it.": {
+			patchComputer: mutating.PatchComputerFunc(func(original, mutated []byte) ([]mutating.JsonPatchOperation, error) {
+				return []mutating.JsonPatchOperation{{Operation: "replace", Path: "/metadata/labels", Value: map[string]string{"test1": "mutated-value1"}}}, nil
+			}),
+			expPatch: `[{"op":"replace","path":"/metadata/labels","value":{"test1":"mutated-value1"}},{"op":"add","path":"/spec/replicas","value":5}]`,
+		},
+
+		"Not mutating the object with JSON patch operations, it should use only the operations without computing the object patch.": {
+			mutator: mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+				return &mutating.MutatorResult{
+					NoMutation: true,
+					JsonPatch:  []mutating.JsonPatchOperation{{Operation: "add", Path: "/spec/replicas", Value: 5}},
+				}, nil
+			}),
+			patchComputer: mutating.PatchComputerFunc(func(original, mutated []byte) ([]mutating.JsonPatchOperation, error) {
+				return nil, fmt.Errorf("should not be called")
+			}),
+			expPatch: `[{"op":"add","path":"/spec/replicas","value":5}]`,
+		},
+
+		"Having an error on the custom patch computer, it should fail.": {
+			patchComputer: mutating.PatchComputerFunc(func(original, mutated []byte) ([]mutating.JsonPatchOperation, error) {
+				return nil, fmt.Errorf("wanted error")
+			}),
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			mutator := test.mutator
+			if mutator == nil {
+				mutator = labelMutator
+			}
+
+			wh, err := mutating.NewWebhook(mutating.WebhookConfig{ID: "test", Mutator: mutator, PatchComputer: test.patchComputer})
+			assert.NoError(err)
+
+			raw := []byte(`{"apiVersion":"example.io/v1","kind":"Foo","metadata":{"name":"test","labels":{"test1":"value1"}},"spec":{}}`)
+			gotResponse, err := wh.Review(context.TODO(), model.AdmissionReview{ID: "test", NewObjectRaw: raw})
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				got := gotResponse.(*model.MutatingAdmissionResponse)
+				assert.Equal(test.expPatch, string(got.JSONPatchPatch))
+			}
+		})
+	}
+}
+
+func TestPodAdmissionReviewSubresource(t *testing.T) {
+	tests := map[string]struct {
+		review   model.AdmissionReview
+		expPatch []string
+	}{
+		"A subresource with the same type of the webhook should be mutated.": {
+			review: model.AdmissionReview{
+				ID:           "test",
+				SubResource:  "status",
+				NewObjectRaw: getPodJSON(),
+			},
+			expPatch: []string{
+				`{"op":"replace","path":"/metadata/namespace","value":"myChangedNS"}`,
+			},
+		},
+
+		"A subresource with a different type of the webhook should be ignored.": {
+			review: model.AdmissionReview{
+				ID:           "test",
+				SubResource:  "eviction",
+				NewObjectRaw: []byte(`{"kind":"Eviction","apiVersion":"policy/v1beta1","metadata":{"name":"testPod","namespace":"myNS"}}`),
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			wh, err := mutating.NewWebhook(mutating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}, Mutator: getPodNSMutator("myChangedNS")})
+			assert.NoError(err)
+
+			gotResponse, err := wh.Review(context.TODO(), test.review)
+			if assert.NoError(err) {
+				got := gotResponse.(*model.MutatingAdmissionResponse)
+				if len(test.expPatch) == 0 {
+					assert.Empty(got.JSONPatchPatch)
+				}
+				for _, expPatchOp := range test.expPatch {
+					assert.Contains(string(got.JSONPatchPatch), expPatchOp)
+				}
+			}
+		})
+	}
+}
+
+func TestNewWebhookConfig(t *testing.T) {
+	mutator := mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, _ metav1.Object) (*mutating.MutatorResult, error) {
+		return &mutating.MutatorResult{}, nil
+	})
+
+	tests := map[string]struct {
+		cfg    mutating.WebhookConfig
+		expErr bool
+	}{
+		"A configuration without ID should fail.": {
+			cfg:    mutating.WebhookConfig{Mutator: mutator},
+			expErr: true,
+		},
+
+		"A configuration without mutator should fail.": {
+			cfg:    mutating.WebhookConfig{ID: "test"},
+			expErr: true,
+		},
+
+		"A configuration with the required fields should not fail.": {
+			cfg: mutating.WebhookConfig{ID: "test", Mutator: mutator},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			wh, err := mutating.NewWebhook(test.cfg)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal("test", wh.ID())
+				assert.Equal(model.WebhookKind(model.WebhookKindMutating), wh.Kind())
+			}
+		})
+	}
+}
+
+func TestPodAdmissionReviewContext(t *testing.T) {
+	tests := map[string]struct {
+		ctx     func() (context.Context, context.CancelFunc)
+		mutator func(cancel context.CancelFunc) mutating.Mutator
+		expErr  bool
+	}{
+		"A mutator should receive the context deadline.": {
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 5*time.Second)
+			},
+			mutator: func(_ context.CancelFunc) mutating.Mutator {
+				return mutating.MutatorFunc(func(ctx context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+					if _, ok := ctx.Deadline(); !ok {
+						return nil, fmt.Errorf("context without deadline")
+					}
+					obj.SetNamespace("myChangedNS")
+					return &mutating.MutatorResult{MutatedObject: obj}, nil
+				})
+			},
+		},
+
+		"An already cancelled context should fail without mutating.": {
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx, cancel
+			},
+			mutator: func(_ context.CancelFunc) mutating.Mutator {
+				return mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, _ metav1.Object) (*mutating.MutatorResult, error) {
+					return nil, fmt.Errorf("should not be called")
+				})
+			},
+			expErr: true,
+		},
+
+		"A context cancelled while mutating should fail.": {
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithCancel(context.Background())
+			},
+			mutator: func(cancel context.CancelFunc) mutating.Mutator {
+				return mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+					cancel()
+					obj.SetNamespace("myChangedNS")
+					return &mutating.MutatorResult{MutatedObject: obj}, nil
+				})
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			ctx, cancel := test.ctx()
+			defer cancel()
+
+			wh, err := mutating.NewWebhook(mutating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}, Mutator: test.mutator(cancel)})
+			assert.NoError(err)
+
+			gotResponse, err := wh.Review(ctx, model.AdmissionReview{ID: "test", NewObjectRaw: getPodJSON()})
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				got := gotResponse.(*model.MutatingAdmissionResponse)
+				assert.NotEmpty(got.JSONPatchPatch)
+			}
+		})
+	}
+}
+
+func TestPodAdmissionReviewAuditAnnotations(t *testing.T) {
+	tests := map[string]struct {
+		mutator             mutating.Mutator
+		expAuditAnnotations map[string]string
+	}{
+		"Audit annotations set on the context should be returned on the response.": {
+			mutator: mutating.MutatorFunc(func(ctx context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+				mutating.SetAuditAnnotation(ctx, "sidecar-injected", "true")
+				obj.SetNamespace("myChangedNS")
+				return &mutating.MutatorResult{MutatedObject: obj}, nil
+			}),
+			expAuditAnnotations: map[string]string{"sidecar-injected": "true"},
+		},
+
+		"Audit annotations set on the context without mutation should be returned on the response.": {
+			mutator: mutating.MutatorFunc(func(ctx context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+				mutating.SetAuditAnnotation(ctx, "sidecar-injected", "false")
+				return &mutating.MutatorResult{NoMutation: true}, nil
+			}),
+			expAuditAnnotations: map[string]string{"sidecar-injected": "false"},
+		},
+
+		"Audit annotations set on a mutator chain should be merged with the result ones, having priority the result ones.": {
+			mutator: mutating.NewChain(log.Noop,
+				mutating.MutatorFunc(func(ctx context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+					mutating.SetAuditAnnotation(ctx, "k1", "v1")
+					mutating.SetAuditAnnotation(ctx, "k2", "v2")
+					return &mutating.MutatorResult{}, nil
+				}),
+				mutating.MutatorFunc(func(ctx context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+					return &mutating.MutatorResult{AuditAnnotations: map[string]string{"k2": "v2-result", "k3": "v3"}}, nil
+				}),
+			),
+			expAuditAnnotations: map[string]string{"k1": "v1", "k2": "v2-result", "k3": "v3"},
+		},
+
+		"Audit annotations set on the context of an allowed on error mutation should be returned on the response.": {
+			mutator: mutating.MutatorFunc(func(ctx context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+				mutating.SetAuditAnnotation(ctx, "sidecar-injected", "false")
+				return nil, fmt.Errorf("something: %w", mutating.ErrAllowOnError)
+			}),
+			expAuditAnnotations: map[string]string{"sidecar-injected": "false"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			wh, err := mutating.NewWebhook(mutating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}, Mutator: test.mutator})
+			assert.NoError(err)
+
+			gotResponse, err := wh.Review(context.TODO(), model.AdmissionReview{ID: "test", NewObjectRaw: getPodJSON()})
+			if assert.NoError(err) {
+				got := gotResponse.(*model.MutatingAdmissionResponse)
+				assert.Equal(test.expAuditAnnotations, got.AuditAnnotations)
+			}
+		})
+	}
+}
+
+// recorderLogger is a logger that records the info messages with their values.
+type recorderLogger struct {
+	log.Logger
+	values log.Kv
+	lines  *[]string
+}
+
+func (r recorderLogger) Infof(format string, args ...interface{}) {
+	*r.lines = append(*r.lines, fmt.Sprintf("%s %v", fmt.Sprintf(format, args...), r.values))
+}
+
+func (r recorderLogger) WithValues(kv log.Kv) log.Logger {
+	values := log.Kv{}
+	for k, v := range r.values {
+		values[k] = v
+	}
+	for k, v := range kv {
+		values[k] = v
+	}
+	return recorderLogger{Logger: r.Logger, values: values, lines: r.lines}
+}
+
+func (r recorderLogger) WithCtxValues(ctx context.Context) log.Logger { return r }
+
+func TestAdmissionReviewPatchLogging(t *testing.T) {
+	mutator := mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+		obj.SetLabels(map[string]string{"team": "team1"})
+		obj.SetAnnotations(map[string]string{"secret": "s3cr3t"})
+		return &mutating.MutatorResult{MutatedObject: obj}, nil
+	})
+
+	tests := map[string]struct {
+		cfg      mutating.WebhookConfig
+		expLines []string
+	}{
+		"Without patch logging it should not log the patch operations.": {
+			cfg:      mutating.WebhookConfig{ID: "test"},
+			expLines: []string{},
+		},
+
+		"With patch logging it should log the patch operations.": {
+			cfg: mutating.WebhookConfig{ID: "test", PatchLogging: true},
+			expLines: []string{
+				"Mutation JSON patch operation map[patch-op:replace patch-path:/metadata/labels/team patch-value:team1 webhook-id:test webhook-kind:mutating]",
+				"Mutation JSON patch operation map[patch-op:add patch-path:/metadata/annotations patch-value:map[secret:s3cr3t] webhook-id:test webhook-kind:mutating]",
+			},
+		},
+
+		"With patch logging and redacted paths it should log the patch operations with the values of the paths redacted.": {
+			cfg: mutating.WebhookConfig{ID: "test", PatchLogging: true, PatchLoggingRedactPath: regexp.MustCompile(`^/metadata/annotations`)},
+			expLines: []string{
+				"Mutation JSON patch operation map[patch-op:replace patch-path:/metadata/labels/team patch-value:team1 webhook-id:test webhook-kind:mutating]",
+				"Mutation JSON patch operation map[patch-op:add patch-path:/metadata/annotations patch-value:[REDACTED] webhook-id:test webhook-kind:mutating]",
+			},
+		},
+
+		"With patch logging and redacted paths it should log the patch operations with the values of the paths parents redacted.": {
+			cfg: mutating.WebhookConfig{ID: "test", PatchLogging: true, PatchLoggingRedactPath: regexp.MustCompile(`^/metadata/annotations/`)},
+			expLines: []string{
+				"Mutation JSON patch operation map[patch-op:replace patch-path:/metadata/labels/team patch-value:team1 webhook-id:test webhook-kind:mutating]",
+				"Mutation JSON patch operation map[patch-op:add patch-path:/metadata/annotations patch-value:[REDACTED] webhook-id:test webhook-kind:mutating]",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			lines := []string{}
+			test.cfg.Logger = recorderLogger{Logger: log.Noop, lines: &lines}
+			test.cfg.Mutator = mutator
+			wh, err := mutating.NewWebhook(test.cfg)
+			assert.NoError(err)
+
+			obj := []byte(`{"kind":"Foo","apiVersion":"example.io/v1","metadata":{"name":"test","labels":{"team":"team0"}}}`)
+			_, err = wh.Review(context.TODO(), model.AdmissionReview{ID: "test", NewObjectRaw: obj})
+			if assert.NoError(err) {
+				assert.ElementsMatch(test.expLines, lines)
+			}
+		})
+	}
+}
+
+func TestAdmissionReviewPatchLoggingRedactSecretData(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	lines := []string{}
+	wh, err := mutating.NewWebhook(mutating.WebhookConfig{
+		ID:                     "test",
+		Obj:                    &corev1.Secret{},
+		Logger:                 recorderLogger{Logger: log.Noop, lines: &lines},
+		PatchLogging:           true,
+		PatchLoggingRedactPath: regexp.MustCompile(`^/data/`),
+		Mutator: mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+			secret := obj.(*corev1.Secret)
+			secret.Data = map[string][]byte{"password": []byte("s3cr3t")}
+			return &mutating.MutatorResult{MutatedObject: secret}, nil
+		}),
+	})
+	require.NoError(err)
+
+	// The whole data map is added on a single operation, the secret values should not be logged.
+	obj := []byte(`{"kind":"Secret","apiVersion":"v1","metadata":{"name":"test","creationTimestamp":null}}`)
+	_, err = wh.Review(context.TODO(), model.AdmissionReview{ID: "test", NewObjectRaw: obj})
+	require.NoError(err)
+
+	expLines := []string{
+		"Mutation JSON patch operation map[patch-op:add patch-path:/data patch-value:[REDACTED] webhook-id:test webhook-kind:mutating]",
+	}
+	assert.Equal(expLines, lines)
+}
+
+func BenchmarkPodAdmissionReviewMutation(b *testing.B) {
+	// Big pod with lots of env vars.
+	pod := &corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test-ns"},
+	}
+	for i := 0; i < 10; i++ {
+		c := corev1.Container{Name: fmt.Sprintf("container-%d", i), Image: "image:latest"}
+		for j := 0; j < 100; j++ {
+			c.Env = append(c.Env, corev1.EnvVar{Name: fmt.Sprintf("ENV_%d", j), Value: fmt.Sprintf("value-%d", j)})
+		}
+		pod.Spec.Containers = append(pod.Spec.Containers, c)
+	}
+	raw, err := json.Marshal(pod)
+	require.NoError(b, err)
+
+	wh, err := mutating.NewWebhook(mutating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}, Mutator: getPodNSMutator("myChangedNS")})
+	require.NoError(b, err)
+	ar := model.AdmissionReview{ID: "test", Operation: model.OperationCreate, NewObjectRaw: raw}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := wh.Review(context.TODO(), ar); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestAdmissionReviewDecodeError(t *testing.T) {
+	tests := map[string]struct {
+		cfg         mutating.WebhookConfig
+		mutatorErr  error
+		review      model.AdmissionReview
+		expResponse model.AdmissionResponse
+		expErr      bool
+	}{
+		"An object that can't be decoded should fail by default.": {
+			cfg:    mutating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}},
+			review: model.AdmissionReview{ID: "test", NewObjectRaw: []byte(`{"kind":"Pod","apiVersion":"v1","spec":`)},
+			expErr: true,
+		},
+
+		"An object that can't be decoded should be allowed without mutation when decode errors are allowed.": {
+			cfg:         mutating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}, AllowOnDecodeError: true},
+			review:      model.AdmissionReview{ID: "test", NewObjectRaw: []byte(`{"kind":"Pod","apiVersion":"v1","spec":`)},
+			expResponse: &model.MutatingAdmissionResponse{ID: "test"},
+		},
+
+		"An object that can't be decoded on a dynamic webhook should be allowed without mutation when decode errors are allowed.": {
+			cfg:         mutating.WebhookConfig{ID: "test", AllowOnDecodeError: true},
+			review:      model.AdmissionReview{ID: "test", NewObjectRaw: []byte(`{"kind":"Pod","apiVersion":"v1","spec":`)},
+			expResponse: &model.MutatingAdmissionResponse{ID: "test"},
+		},
+
+		"An object of a different type of the webhook should be allowed without mutation when decode errors are allowed.": {
+			cfg:         mutating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}, AllowOnDecodeError: true},
+			review:      model.AdmissionReview{ID: "test", NewObjectRaw: []byte(`{"kind":"Service","apiVersion":"v1","metadata":{"name":"test"}}`)},
+			expResponse: &model.MutatingAdmissionResponse{ID: "test"},
+		},
+
+		"A mutator error should fail when decode errors are allowed.": {
+			cfg:        mutating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}, AllowOnDecodeError: true},
+			mutatorErr: fmt.Errorf("wanted error"),
+			review:     model.AdmissionReview{ID: "test", NewObjectRaw: getPodJSON()},
+			expErr:     true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			test.cfg.Mutator = mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+				if test.mutatorErr != nil {
+					return nil, test.mutatorErr
+				}
+				obj.SetLabels(map[string]string{"mutated": "true"})
+				return &mutating.MutatorResult{MutatedObject: obj}, nil
+			})
+			wh, err := mutating.NewWebhook(test.cfg)
+			require.NoError(err)
+
+			gotResponse, err := wh.Review(context.TODO(), test.review)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expResponse, gotResponse)
+			}
+		})
+	}
+}
+
+func TestFailOpenWebhookMutatorPanic(t *testing.T) {
+	tests := map[string]struct {
+		cfg         mutating.WebhookConfig
+		expResponse model.AdmissionResponse
+		expPanic    bool
+	}{
+		"A fail open webhook with a mutator that panics should allow the resource by default.": {
+			cfg:         mutating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}},
+			expResponse: &model.MutatingAdmissionResponse{ID: "test"},
+		},
+
+		"A fail open webhook with a mutator that panics should panic when the panic recovery is disabled.": {
+			cfg:      mutating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}, DisablePanicRecovery: true},
+			expPanic: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			test.cfg.Mutator = mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+				panic("wanted panic")
+			})
+			wh, err := mutating.NewWebhook(test.cfg)
+			require.NoError(err)
+			wh = webhook.NewFailOpenWebhook(log.Noop, wh)
+
+			review := model.AdmissionReview{ID: "test", NewObjectRaw: getPodJSON()}
+			if test.expPanic {
+				assert.Panics(func() { _, _ = wh.Review(context.TODO(), review) })
+				return
+			}
+
+			gotResponse, err := wh.Review(context.TODO(), review)
+			if assert.NoError(err) {
+				assert.Equal(test.expResponse, gotResponse)
+			}
+		})
+	}
+}
+
+func TestUnstructuredAdmissionReviewMutation(t *testing.T) {
+	tests := map[string]struct {
+		cfg      mutating.WebhookConfig
+		expPatch string
+	}{
+		"An unstructured webhook should mutate any kind.": {
+			cfg:      mutating.WebhookConfig{ID: "test", Obj: &unstructured.Unstructured{}},
+			expPatch: `[{"op":"add","path":"/metadata/labels","value":{"mutated":"true"}}]`,
+		},
+
+		"An unstructured webhook should mutate any kind when decode errors are allowed.": {
+			cfg:      mutating.WebhookConfig{ID: "test", Obj: &unstructured.Unstructured{}, AllowOnDecodeError: true},
+			expPatch: `[{"op":"add","path":"/metadata/labels","value":{"mutated":"true"}}]`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			test.cfg.Mutator = mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+				obj.SetLabels(map[string]string{"mutated": "true"})
+				return &mutating.MutatorResult{MutatedObject: obj}, nil
+			})
+			wh, err := mutating.NewWebhook(test.cfg)
+			require.NoError(err)
+
+			obj := []byte(`{"kind":"Foo","apiVersion":"example.io/v1","metadata":{"name":"test"}}`)
+			gotResponse, err := wh.Review(context.TODO(), model.AdmissionReview{ID: "test", NewObjectRaw: obj})
+			if assert.NoError(err) {
+				got := gotResponse.(*model.MutatingAdmissionResponse)
+				assert.Equal(test.expPatch, string(got.JSONPatchPatch))
+			}
+		})
+	}
+}
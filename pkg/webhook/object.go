@@ -0,0 +1,38 @@
+package webhook
+
+import (
+	"fmt"
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AsObject sets the received object on the target if the object type is the target type, the target
+// must be a pointer to a variable of the expected type, e.g:
+//
+//	var pod *corev1.Pod
+//	if err := webhook.AsObject(obj, &pod); err != nil {
+//		return nil, err
+//	}
+//
+// If the object is not of the target type it will return a descriptive error with both types
+// (e.g: `expected *v1.Pod, got *v1.Deployment`).
+func AsObject(obj metav1.Object, target interface{}) error {
+	tv := reflect.ValueOf(target)
+	if tv.Kind() != reflect.Ptr || tv.IsNil() {
+		return fmt.Errorf("target must be a non-nil pointer, got %T", target)
+	}
+
+	targetType := tv.Type().Elem()
+	if obj == nil {
+		return fmt.Errorf("expected %s, got nil", targetType)
+	}
+
+	ov := reflect.ValueOf(obj)
+	if !ov.Type().AssignableTo(targetType) {
+		return fmt.Errorf("expected %s, got %T", targetType, obj)
+	}
+	tv.Elem().Set(ov)
+
+	return nil
+}
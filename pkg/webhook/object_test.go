@@ -0,0 +1,86 @@
+package webhook_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/slok/kubewebhook/v2/pkg/webhook"
+)
+
+func TestAsObject(t *testing.T) {
+	tests := map[string]struct {
+		obj    metav1.Object
+		as     func(obj metav1.Object) (metav1.Object, error)
+		expObj metav1.Object
+		expErr string
+	}{
+		"An object of the target type should be set on the target.": {
+			obj: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test"}},
+			as: func(obj metav1.Object) (metav1.Object, error) {
+				var pod *corev1.Pod
+				err := webhook.AsObject(obj, &pod)
+				return pod, err
+			},
+			expObj: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test"}},
+		},
+
+		"An object of a different type should return a descriptive error.": {
+			obj: &appsv1.Deployment{},
+			as: func(obj metav1.Object) (metav1.Object, error) {
+				var pod *corev1.Pod
+				err := webhook.AsObject(obj, &pod)
+				return nil, err
+			},
+			expErr: "expected *v1.Pod, got *v1.Deployment",
+		},
+
+		"An unstructured object should return a descriptive error when a typed object is expected.": {
+			obj: &unstructured.Unstructured{},
+			as: func(obj metav1.Object) (metav1.Object, error) {
+				var pod *corev1.Pod
+				err := webhook.AsObject(obj, &pod)
+				return nil, err
+			},
+			expErr: "expected *v1.Pod, got *unstructured.Unstructured",
+		},
+
+		"A missing object should return an error.": {
+			obj: nil,
+			as: func(obj metav1.Object) (metav1.Object, error) {
+				var pod *corev1.Pod
+				err := webhook.AsObject(obj, &pod)
+				return nil, err
+			},
+			expErr: "expected *v1.Pod, got nil",
+		},
+
+		"A nil target should return an error.": {
+			obj: &corev1.Pod{},
+			as: func(obj metav1.Object) (metav1.Object, error) {
+				var pod *corev1.Pod
+				err := webhook.AsObject(obj, pod)
+				return nil, err
+			},
+			expErr: "target must be a non-nil pointer, got *v1.Pod",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			gotObj, err := test.as(test.obj)
+
+			if test.expErr != "" {
+				assert.EqualError(err, test.expErr)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expObj, gotObj)
+			}
+		})
+	}
+}
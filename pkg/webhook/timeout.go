@@ -0,0 +1,64 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/slok/kubewebhook/v2/pkg/model"
+)
+
+type timeoutWebhook struct {
+	timeout time.Duration
+	next    Webhook
+}
+
+// NewTimeoutWebhook returns a wrapped webhook that will end the review if it takes more than
+// the timeout. The context received by the wrapped webhook will be cancelled on the timeout (and
+// when the review ends), so the mutators and validators can stop their work.
+//
+// The wrapped review can't be stopped by other means, the mutators and validators must honor
+// the context, otherwise they will keep running in background after the timeout and their
+// result will be discarded.
+//
+// On timeout the review will return a `StatusError` with a timeout reason, this way the webhook
+// can answer the apiserver with a not allowed response before the apiserver webhook timeout is
+// reached (`timeoutSeconds`).
+func NewTimeoutWebhook(timeout time.Duration, next Webhook) Webhook {
+	return timeoutWebhook{
+		timeout: timeout,
+		next:    next,
+	}
+}
+
+func (t timeoutWebhook) ID() string              { return t.next.ID() }
+func (t timeoutWebhook) Kind() model.WebhookKind { return t.next.Kind() }
+func (t timeoutWebhook) Review(ctx context.Context, ar model.AdmissionReview) (model.AdmissionResponse, error) {
+	// The review context is cancelled on timeout and when we return, this way the
+	// reviews that honor the context will not keep running after the timeout.
+	reviewCtx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	type result struct {
+		resp model.AdmissionResponse
+		err  error
+	}
+
+	// Buffered so the review can finish (and be garbage collected) after the timeout.
+	resC := make(chan result, 1)
+	go func() {
+		resp, err := t.next.Review(reviewCtx, ar)
+		resC <- result{resp: resp, err: err}
+	}()
+
+	select {
+	case res := <-resC:
+		return res.resp, res.err
+	case <-reviewCtx.Done():
+		msg := fmt.Sprintf("webhook review timeout after %s", t.timeout)
+		return nil, NewStatusError(http.StatusGatewayTimeout, metav1.StatusReasonTimeout, msg)
+	}
+}
@@ -0,0 +1,98 @@
+package webhook_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/webhook"
+	"github.com/slok/kubewebhook/v2/pkg/webhook/webhookmock"
+)
+
+func TestTimeoutWebhook(t *testing.T) {
+	tests := map[string]struct {
+		reviewDuration time.Duration
+		resp           model.AdmissionResponse
+		err            error
+		expResp        model.AdmissionResponse
+		expErr         error
+	}{
+		"A review that finishes in time should return the review response.": {
+			resp:    &model.ValidatingAdmissionResponse{ID: "test", Allowed: true},
+			expResp: &model.ValidatingAdmissionResponse{ID: "test", Allowed: true},
+		},
+
+		"A review that finishes in time with an error should return the review error.": {
+			err:    fmt.Errorf("something"),
+			expErr: fmt.Errorf("something"),
+		},
+
+		"A review that doesn't finish in time should return a timeout status error.": {
+			reviewDuration: 500 * time.Millisecond,
+			resp:           &model.ValidatingAdmissionResponse{ID: "test", Allowed: true},
+			expErr:         webhook.NewStatusError(http.StatusGatewayTimeout, metav1.StatusReasonTimeout, "webhook review timeout after 50ms"),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			mw := &webhookmock.Webhook{}
+			mw.On("Review", mock.Anything, mock.Anything).Once().
+				Run(func(args mock.Arguments) {
+					// Simulate a review that honors the context.
+					ctx := args.Get(0).(context.Context)
+					select {
+					case <-time.After(test.reviewDuration):
+					case <-ctx.Done():
+					}
+				}).
+				Return(test.resp, test.err)
+
+			wh := webhook.NewTimeoutWebhook(50*time.Millisecond, mw)
+			gotResp, err := wh.Review(context.TODO(), model.AdmissionReview{ID: "test"})
+
+			if test.expErr != nil && assert.Error(err) {
+				assert.Equal(test.expErr, err)
+			} else {
+				assert.NoError(err)
+				assert.Equal(test.expResp, gotResp)
+			}
+		})
+	}
+}
+
+func TestTimeoutWebhookCancelsReview(t *testing.T) {
+	assert := assert.New(t)
+
+	// The review ignores the timeout, but it should receive a cancelled context when
+	// the timeout webhook returns.
+	reviewCtxC := make(chan context.Context, 1)
+	mw := &webhookmock.Webhook{}
+	mw.On("Review", mock.Anything, mock.Anything).Once().
+		Run(func(args mock.Arguments) {
+			ctx := args.Get(0).(context.Context)
+			time.Sleep(100 * time.Millisecond)
+			reviewCtxC <- ctx
+		}).
+		Return(nil, nil)
+
+	wh := webhook.NewTimeoutWebhook(10*time.Millisecond, mw)
+	_, err := wh.Review(context.TODO(), model.AdmissionReview{ID: "test"})
+	assert.Error(err)
+
+	select {
+	case ctx := <-reviewCtxC:
+		assert.Equal(context.DeadlineExceeded, ctx.Err())
+	case <-time.After(time.Second):
+		assert.Fail("review not finished")
+	}
+}
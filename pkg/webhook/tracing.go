@@ -0,0 +1,46 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/tracing"
+)
+
+type tracedWebhook struct {
+	tracer tracing.Tracer
+	next   Webhook
+}
+
+// NewTracedWebhook returns a wrapped webhook that will trace the webhook reviews. The trace will
+// be the parent of the traces of the wrapped webhook.
+func NewTracedWebhook(tracer tracing.Tracer, next Webhook) Webhook {
+	if tracer == nil {
+		tracer = tracing.Noop
+	}
+
+	return tracedWebhook{
+		tracer: tracer,
+		next:   next,
+	}
+}
+
+func (t tracedWebhook) ID() string              { return t.next.ID() }
+func (t tracedWebhook) Kind() model.WebhookKind { return t.next.Kind() }
+func (t tracedWebhook) Review(ctx context.Context, ar model.AdmissionReview) (resp model.AdmissionResponse, err error) {
+	ctx = t.tracer.NewTrace(ctx, "webhook.Review")
+	defer func() { t.tracer.EndTrace(ctx, err) }()
+
+	t.tracer.SetValuesOnTrace(ctx, map[string]interface{}{
+		"webhook-id":   t.next.ID(),
+		"webhook-kind": string(t.next.Kind()),
+		"request-id":   ar.ID,
+		"op":           string(ar.Operation),
+		"kind":         reviewResourceKind(ar),
+		"ns":           ar.Namespace,
+		"name":         ar.Name,
+		"dry-run":      ar.DryRun,
+	})
+
+	return t.next.Review(ctx, ar)
+}
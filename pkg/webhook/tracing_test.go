@@ -0,0 +1,95 @@
+package webhook_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/webhook"
+	"github.com/slok/kubewebhook/v2/pkg/webhook/webhookmock"
+)
+
+type traceKey struct{}
+
+// recorderTracer is a tracer that records the traces.
+type recorderTracer struct {
+	traces *[]string
+	values map[string]interface{}
+}
+
+func (r recorderTracer) NewTrace(ctx context.Context, name string) context.Context {
+	*r.traces = append(*r.traces, "start "+name)
+	return context.WithValue(ctx, traceKey{}, name)
+}
+
+func (r recorderTracer) NewHTTPTrace(req *http.Request, name string) context.Context {
+	return r.NewTrace(req.Context(), name)
+}
+
+func (r recorderTracer) SetValuesOnTrace(ctx context.Context, values map[string]interface{}) {
+	for k, v := range values {
+		r.values[k] = v
+	}
+}
+
+func (r recorderTracer) EndTrace(ctx context.Context, err error) {
+	*r.traces = append(*r.traces, fmt.Sprintf("end %v %v", ctx.Value(traceKey{}), err))
+}
+
+func TestTracedWebhook(t *testing.T) {
+	tests := map[string]struct {
+		err       error
+		expTraces []string
+	}{
+		"A successful review should be traced.": {
+			expTraces: []string{"start webhook.Review", "end webhook.Review <nil>"},
+		},
+
+		"A failed review should be traced with the error.": {
+			err:       fmt.Errorf("something"),
+			expTraces: []string{"start webhook.Review", "end webhook.Review something"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			review := model.AdmissionReview{ID: "test", Name: "test-name", Namespace: "test-ns", Operation: model.OperationCreate}
+
+			// Mocks.
+			mwh := &webhookmock.Webhook{}
+			mwh.On("ID").Return("test-wh")
+			mwh.On("Kind").Return(model.WebhookKind(model.WebhookKindMutating))
+			// The wrapped webhook should receive the trace on the context.
+			isTraced := mock.MatchedBy(func(ctx context.Context) bool { return ctx.Value(traceKey{}) == "webhook.Review" })
+			mwh.On("Review", isTraced, review).Once().Return(nil, test.err)
+
+			// Execute.
+			traces := []string{}
+			tracer := recorderTracer{traces: &traces, values: map[string]interface{}{}}
+			wh := webhook.NewTracedWebhook(tracer, mwh)
+			_, err := wh.Review(context.TODO(), review)
+
+			// Check.
+			assert.Equal(test.err, err)
+			assert.Equal(test.expTraces, traces)
+			assert.Equal(map[string]interface{}{
+				"webhook-id":   "test-wh",
+				"webhook-kind": "mutating",
+				"request-id":   "test",
+				"op":           "create",
+				"kind":         "",
+				"ns":           "test-ns",
+				"name":         "test-name",
+				"dry-run":      false,
+			}, tracer.values)
+			mwh.AssertExpectations(t)
+		})
+	}
+}
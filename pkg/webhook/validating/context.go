@@ -0,0 +1,30 @@
+package validating
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type contextKey string
+
+// contextOldObjectKey used as unique key to store the old object in the context.
+const contextOldObjectKey = contextKey("kubewebhook-validating-old-object")
+
+// OldObjectFromContext returns the old object of the admission review being validated
+// (e.g on `update` operations). The returned object is a decoded copy of the old object
+// from the review and has the same type as the object received by the validator.
+//
+// On operations that don't have an old object (e.g `create`) it will return `nil`.
+func OldObjectFromContext(ctx context.Context) metav1.Object {
+	obj, ok := ctx.Value(contextOldObjectKey).(metav1.Object)
+	if !ok {
+		return nil
+	}
+
+	return obj
+}
+
+func contextWithOldObject(parent context.Context, obj metav1.Object) context.Context {
+	return context.WithValue(parent, contextOldObjectKey, obj)
+}
@@ -74,3 +74,118 @@ func ExampleValidator_chainValidatingWebhook() {
 	})
 
 }
+
+// immutableLabelValidatingWebhook shows how you would create a dynamic validating webhook (any
+// resource type) that uses the old object of the review to deny the changes on a label.
+func ExampleValidator_immutableLabelValidatingWebhook() {
+	const immutableLabel = "team"
+
+	val := validating.ValidatorFunc(func(ctx context.Context, _ *model.AdmissionReview, obj metav1.Object) (*validating.ValidatorResult, error) {
+		// Only updates have old object.
+		oldObj := validating.OldObjectFromContext(ctx)
+		if oldObj == nil {
+			return &validating.ValidatorResult{Valid: true}, nil
+		}
+
+		if oldObj.GetLabels()[immutableLabel] != obj.GetLabels()[immutableLabel] {
+			return &validating.ValidatorResult{
+				Valid:   false,
+				Message: fmt.Sprintf("%q label is immutable", immutableLabel),
+			}, nil
+		}
+
+		return &validating.ValidatorResult{Valid: true}, nil
+	})
+
+	// Don't set the object type, this webhook will validate any resource type.
+	_, _ = validating.NewWebhook(validating.WebhookConfig{
+		ID:        "immutableLabelWebhook",
+		Validator: val,
+	})
+}
+
+// privilegedPodValidatingWebhook shows how you would create a pod validating webhook that only
+// allows the users of a group to create privileged pods, using the user info of the review.
+func ExampleValidator_privilegedPodValidatingWebhook() {
+	const privilegedGroup = "system:privileged-users"
+
+	val := validating.ValidatorFunc(func(_ context.Context, ar *model.AdmissionReview, obj metav1.Object) (*validating.ValidatorResult, error) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok {
+			return &validating.ValidatorResult{Valid: true}, nil
+		}
+
+		privileged := false
+		for _, c := range pod.Spec.Containers {
+			if c.SecurityContext != nil && c.SecurityContext.Privileged != nil && *c.SecurityContext.Privileged {
+				privileged = true
+			}
+		}
+		if !privileged {
+			return &validating.ValidatorResult{Valid: true}, nil
+		}
+
+		for _, group := range ar.UserInfo.Groups {
+			if group == privilegedGroup {
+				return &validating.ValidatorResult{Valid: true}, nil
+			}
+		}
+
+		return &validating.ValidatorResult{
+			Valid:   false,
+			Message: fmt.Sprintf("%q user is not allowed to create privileged pods", ar.UserInfo.Username),
+		}, nil
+	})
+
+	_, _ = validating.NewWebhook(validating.WebhookConfig{
+		ID:        "privilegedPodWebhook",
+		Obj:       &corev1.Pod{},
+		Validator: val,
+	})
+}
+
+// gvkRouterValidatingWebhook shows how you would create a dynamic webhook that validates
+// different types (including custom resources) with a different validator for each type.
+func ExampleValidator_gvkRouterValidatingWebhook() {
+	// Don't allow pods without containers.
+	podVal := validating.ValidatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*validating.ValidatorResult, error) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok || len(pod.Spec.Containers) == 0 {
+			return &validating.ValidatorResult{Valid: false, Message: "pods require containers"}, nil
+		}
+
+		return &validating.ValidatorResult{Valid: true}, nil
+	})
+
+	// Don't allow node port services.
+	svcVal := validating.ValidatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*validating.ValidatorResult, error) {
+		svc, ok := obj.(*corev1.Service)
+		if ok && svc.Spec.Type == corev1.ServiceTypeNodePort {
+			return &validating.ValidatorResult{Valid: false, Message: "node port services are not allowed"}, nil
+		}
+
+		return &validating.ValidatorResult{Valid: true}, nil
+	})
+
+	// Unknown types (e.g custom resources) are received as unstructured objects, require an owner label.
+	ownerVal := validating.ValidatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*validating.ValidatorResult, error) {
+		if obj.GetLabels()["owner"] == "" {
+			return &validating.ValidatorResult{Valid: false, Message: "owner label is required"}, nil
+		}
+
+		return &validating.ValidatorResult{Valid: true}, nil
+	})
+
+	// Route each kind to its validator, the rest of the kinds will be allowed.
+	router := validating.NewGVKRouter(log.Noop, map[metav1.GroupVersionKind]validating.Validator{
+		{Version: "v1", Kind: "Pod"}:                                  podVal,
+		{Version: "v1", Kind: "Service"}:                              svcVal,
+		{Group: "example.com", Version: "v1", Kind: "CustomResource"}: ownerVal,
+	}, nil)
+
+	// Create a dynamic webhook (no object type).
+	_, _ = validating.NewWebhook(validating.WebhookConfig{
+		ID:        "multiKindValidatingWebhook",
+		Validator: router,
+	})
+}
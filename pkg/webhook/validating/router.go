@@ -0,0 +1,73 @@
+package validating
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/slok/kubewebhook/v2/pkg/log"
+	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/webhook/internal/helpers"
+)
+
+// GVKRouter is a validator that routes the validation to a different validator based on the
+// kind (GVK) of the reviewed object, this is useful on dynamic webhooks that
+// validate multiple types. It satisfies Validator interface.
+type GVKRouter struct {
+	routes   map[metav1.GroupVersionKind]Validator
+	fallback Validator
+	logger   log.Logger
+}
+
+// NewGVKRouter returns a new GVKRouter. The reviews whose kind doesn't match any of the routes
+// will be validated by the fallback validator, if the fallback is `nil` they will be allowed.
+//
+// Empty group and version on the routes will match any group and version (e.g `{Kind: "Pod"}`
+// will match all the pods).
+func NewGVKRouter(logger log.Logger, routes map[metav1.GroupVersionKind]Validator, fallback Validator) *GVKRouter {
+	if logger == nil {
+		logger = log.Noop
+	}
+
+	return &GVKRouter{
+		routes:   routes,
+		fallback: fallback,
+		logger:   logger,
+	}
+}
+
+// Validate will execute the validator that matches the admission review kind.
+func (r *GVKRouter) Validate(ctx context.Context, ar *model.AdmissionReview, obj metav1.Object) (*ValidatorResult, error) {
+	if v, ok := r.route(*ar); ok {
+		return v.Validate(ctx, ar, obj)
+	}
+
+	if r.fallback == nil {
+		r.logger.WithCtxValues(ctx).Debugf("No validator route for the kind, allowing resource")
+		return &ValidatorResult{Valid: true}, nil
+	}
+
+	return r.fallback.Validate(ctx, ar, obj)
+}
+
+// route returns the validator of the admission review kind. The kind of the reviewed object (could be converted
+// from the requested kind, e.g `matchPolicy: Equivalent`) has priority over the requested kind, and the routes
+// with empty group or version will match any group or version.
+func (r *GVKRouter) route(ar model.AdmissionReview) (Validator, bool) {
+	gvks := helpers.ReviewGVKs(ar)
+	for _, gvk := range gvks {
+		if v, ok := r.routes[gvk]; ok {
+			return v, true
+		}
+	}
+
+	for _, gvk := range gvks {
+		for rgvk, v := range r.routes {
+			if helpers.GVKMatches(rgvk, gvk) {
+				return v, true
+			}
+		}
+	}
+
+	return nil, false
+}
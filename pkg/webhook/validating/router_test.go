@@ -0,0 +1,123 @@
+package validating_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/slok/kubewebhook/v2/pkg/log"
+	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/webhook/validating"
+	"github.com/slok/kubewebhook/v2/pkg/webhook/validating/validatingmock"
+)
+
+func TestGVKRouter(t *testing.T) {
+	podGVK := metav1.GroupVersionKind{Kind: "Pod"}
+	svcGVK := metav1.GroupVersionKind{Version: "v1", Kind: "Service"}
+	deployGVK := metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+
+	tests := map[string]struct {
+		review    model.AdmissionReview
+		nilLogger bool
+		fallback  bool
+		mock      func(mpod, msvc, mfallback *validatingmock.Validator)
+		expResult *validating.ValidatorResult
+	}{
+		"A review of a routed kind should be validated by the kind validator.": {
+			review: model.AdmissionReview{RequestGVK: &svcGVK},
+			mock: func(mpod, msvc, mfallback *validatingmock.Validator) {
+				msvc.On("Validate", mock.Anything, mock.Anything, mock.Anything).Once().Return(&validating.ValidatorResult{Valid: false, Message: "svc"}, nil)
+			},
+			expResult: &validating.ValidatorResult{Valid: false, Message: "svc"},
+		},
+
+		"A review of a kind that matches a route without group and version should be validated by the route validator.": {
+			review: model.AdmissionReview{RequestGVK: &metav1.GroupVersionKind{Version: "v1", Kind: "Pod"}},
+			mock: func(mpod, msvc, mfallback *validatingmock.Validator) {
+				mpod.On("Validate", mock.Anything, mock.Anything, mock.Anything).Once().Return(&validating.ValidatorResult{Valid: false, Message: "pod"}, nil)
+			},
+			expResult: &validating.ValidatorResult{Valid: false, Message: "pod"},
+		},
+
+		"A review of an object converted from the requested kind should be validated by the object kind validator.": {
+			review: model.AdmissionReview{
+				RequestGVK:   &metav1.GroupVersionKind{Version: "v1beta1", Kind: "Service"},
+				NewObjectRaw: []byte(`{"kind":"Service","apiVersion":"v1"}`),
+			},
+			mock: func(mpod, msvc, mfallback *validatingmock.Validator) {
+				msvc.On("Validate", mock.Anything, mock.Anything, mock.Anything).Once().Return(&validating.ValidatorResult{Valid: false, Message: "svc"}, nil)
+			},
+			expResult: &validating.ValidatorResult{Valid: false, Message: "svc"},
+		},
+
+		"A review of a not routed kind without fallback should be allowed.": {
+			review:    model.AdmissionReview{RequestGVK: &deployGVK},
+			mock:      func(mpod, msvc, mfallback *validatingmock.Validator) {},
+			expResult: &validating.ValidatorResult{Valid: true},
+		},
+
+		"A review of a not routed kind without fallback and without logger should be allowed.": {
+			review:    model.AdmissionReview{RequestGVK: &deployGVK},
+			nilLogger: true,
+			mock:      func(mpod, msvc, mfallback *validatingmock.Validator) {},
+			expResult: &validating.ValidatorResult{Valid: true},
+		},
+
+		"A review of a not routed kind with fallback should be validated by the fallback validator.": {
+			review:   model.AdmissionReview{RequestGVK: &deployGVK},
+			fallback: true,
+			mock: func(mpod, msvc, mfallback *validatingmock.Validator) {
+				mfallback.On("Validate", mock.Anything, mock.Anything, mock.Anything).Once().Return(&validating.ValidatorResult{Valid: false, Message: "fallback"}, nil)
+			},
+			expResult: &validating.ValidatorResult{Valid: false, Message: "fallback"},
+		},
+
+		"A review without kind with fallback should be validated by the fallback validator.": {
+			review:   model.AdmissionReview{},
+			fallback: true,
+			mock: func(mpod, msvc, mfallback *validatingmock.Validator) {
+				mfallback.On("Validate", mock.Anything, mock.Anything, mock.Anything).Once().Return(&validating.ValidatorResult{Valid: false, Message: "fallback"}, nil)
+			},
+			expResult: &validating.ValidatorResult{Valid: false, Message: "fallback"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mocks.
+			mpod, msvc, mfallback := &validatingmock.Validator{}, &validatingmock.Validator{}, &validatingmock.Validator{}
+			test.mock(mpod, msvc, mfallback)
+
+			// Prepare.
+			var fallback validating.Validator
+			if test.fallback {
+				fallback = mfallback
+			}
+			routes := map[metav1.GroupVersionKind]validating.Validator{
+				podGVK: mpod,
+				svcGVK: msvc,
+			}
+			var logger log.Logger = log.Noop
+			if test.nilLogger {
+				logger = nil
+			}
+			router := validating.NewGVKRouter(logger, routes, fallback)
+
+			// Execute.
+			gotResult, err := router.Validate(context.TODO(), &test.review, nil)
+
+			// Check.
+			if assert.NoError(err) {
+				assert.Equal(test.expResult, gotResult)
+			}
+			mpod.AssertExpectations(t)
+			msvc.AssertExpectations(t)
+			mfallback.AssertExpectations(t)
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/slok/kubewebhook/v2/pkg/log"
 	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/webhook/internal/helpers"
 )
 
 // ValidatorResult is the result of a validator.
@@ -18,8 +19,19 @@ type ValidatorResult struct {
 	Valid bool
 	// Message will be used by the apiserver to give more information in case the resource is not valid.
 	Message string
+	// StatusCode is the HTTP like status code (e.g 403) that will be returned to the apiserver in case
+	// the resource is not valid. If not set, it will default to 400 (bad request).
+	StatusCode int32
+	// Violations are the field violations of the resource in case the resource is not valid, this is
+	// useful to report multiple reasons at once. If the message is not set, it will be generated
+	// using the violations.
+	Violations []model.Violation
 	// Warnings are special messages that can be set to warn the user (e.g deprecation messages, almost invalid resources...).
+	// Warnings are only supported by `v1` admission reviews, on `v1beta1` they will be ignored.
 	Warnings []string
+	// AuditAnnotations are key values that will be added to the audit event of the request on
+	// the apiserver audit logs, the keys will be prefixed by the apiserver with the webhook name.
+	AuditAnnotations map[string]string
 }
 
 // Validator knows how to validate the received kubernetes object.
@@ -30,6 +42,8 @@ type Validator interface {
 	// information of the review.
 	// Validators can be grouped in chains, that's why we have a `StopChain` boolean
 	// in the result, to stop executing the validators chain.
+	// Validators must honor the context cancellation (e.g review timeouts), a validator that
+	// ignores it can't be stopped and will keep running after the review has ended.
 	Validate(ctx context.Context, ar *model.AdmissionReview, obj metav1.Object) (result *ValidatorResult, err error)
 }
 
@@ -43,6 +57,16 @@ func (f ValidatorFunc) Validate(ctx context.Context, ar *model.AdmissionReview,
 	return f(ctx, ar, obj)
 }
 
+// NoopValidator is a validator that always allows, it can be used as a placeholder (e.g
+// on conditional chains) or for testing.
+const NoopValidator = noopValidator(0)
+
+type noopValidator int
+
+func (noopValidator) Validate(_ context.Context, _ *model.AdmissionReview, _ metav1.Object) (*ValidatorResult, error) {
+	return &ValidatorResult{Valid: true}, nil
+}
+
 type chain struct {
 	validators []Validator
 	logger     log.Logger
@@ -62,6 +86,7 @@ func NewChain(logger log.Logger, validators ...Validator) Validator {
 // Validate will execute all the validation chain.
 func (c chain) Validate(ctx context.Context, ar *model.AdmissionReview, obj metav1.Object) (*ValidatorResult, error) {
 	var warnings []string
+	var auditAnnotations map[string]string
 	for _, vl := range c.validators {
 		select {
 		case <-ctx.Done():
@@ -78,16 +103,19 @@ func (c chain) Validate(ctx context.Context, ar *model.AdmissionReview, obj meta
 
 			// Don't lose the warnings through the chain.
 			warnings = append(warnings, res.Warnings...)
+			auditAnnotations = helpers.MergeAuditAnnotations(auditAnnotations, res.AuditAnnotations)
 
 			if res.StopChain || !res.Valid {
 				res.Warnings = warnings
+				res.AuditAnnotations = auditAnnotations
 				return res, nil
 			}
 		}
 	}
 
 	return &ValidatorResult{
-		Valid:    true,
-		Warnings: warnings,
+		Valid:            true,
+		Warnings:         warnings,
+		AuditAnnotations: auditAnnotations,
 	}, nil
 }
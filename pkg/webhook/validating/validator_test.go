@@ -82,6 +82,20 @@ func TestValidatorChain(t *testing.T) {
 			},
 		},
 
+		"Audit annotations shouldn't be lost in the chain.": {
+			validatorMocks: func() []validating.Validator {
+				m1, m2, m3 := &validatingmock.Validator{}, &validatingmock.Validator{}, &validatingmock.Validator{}
+				m1.On("Validate", mock.Anything, mock.Anything, mock.Anything).Return(&validating.ValidatorResult{Valid: true, AuditAnnotations: map[string]string{"k1": "v1", "k2": "v2"}}, nil)
+				m2.On("Validate", mock.Anything, mock.Anything, mock.Anything).Return(&validating.ValidatorResult{Valid: true}, nil)
+				m3.On("Validate", mock.Anything, mock.Anything, mock.Anything).Return(&validating.ValidatorResult{Valid: true, AuditAnnotations: map[string]string{"k2": "v2b", "k3": "v3"}}, nil)
+				return []validating.Validator{m1, m2, m3}
+			},
+			expResult: &validating.ValidatorResult{
+				Valid:            true,
+				AuditAnnotations: map[string]string{"k1": "v1", "k2": "v2b", "k3": "v3"},
+			},
+		},
+
 		"Warning messages shouldn't be lost in the chain (stopped chain by invalid).": {
 			validatorMocks: func() []validating.Validator {
 				m1, m2, m3, m4, m5 := &validatingmock.Validator{}, &validatingmock.Validator{}, &validatingmock.Validator{}, &validatingmock.Validator{}, &validatingmock.Validator{}
@@ -138,3 +152,13 @@ func TestValidatorChain(t *testing.T) {
 		})
 	}
 }
+
+func TestNoopValidator(t *testing.T) {
+	assert := assert.New(t)
+
+	chain := validating.NewChain(log.Noop, validating.NoopValidator, validating.NoopValidator)
+	res, err := chain.Validate(context.TODO(), nil, nil)
+	if assert.NoError(err) {
+		assert.Equal(&validating.ValidatorResult{Valid: true}, res)
+	}
+}
@@ -3,11 +3,14 @@ package validating
 import (
 	"context"
 	"fmt"
+	"runtime/debug"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 
 	"github.com/slok/kubewebhook/v2/pkg/log"
 	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/tracing"
 	"github.com/slok/kubewebhook/v2/pkg/webhook"
 	"github.com/slok/kubewebhook/v2/pkg/webhook/internal/helpers"
 )
@@ -17,12 +20,30 @@ type WebhookConfig struct {
 	// ID is the id of the webhook.
 	ID string
 	// Object is the object of the webhook, to use multiple types on the same webhook or
-	// type inference, don't set this field (will be `nil`).
+	// type inference, don't set this field (will be `nil`). The inferred objects of types not
+	// registered on the scheme (e.g CRDs) will be received as `*unstructured.Unstructured`.
 	Obj metav1.Object
 	// Validator is the webhook validator.
 	Validator Validator
 	// Logger is the app logger.
 	Logger log.Logger
+	// Tracer is the tracer used to trace the webhook reviews, by default it will not trace.
+	Tracer tracing.Tracer
+	// Scheme is the scheme used to infer the types when `Obj` is not set (e.g CRDs). If
+	// not set it will use the Kubernetes client scheme (Kubernetes types, including the legacy
+	// group versions like `extensions/v1beta1`). When set, the Kubernetes types will need to
+	// be registered on it too if required (e.g `clientgoscheme.AddToScheme`).
+	Scheme *runtime.Scheme
+	// Decoder is the decoder used to decode the raw objects of the admission review (e.g CRDs with
+	// custom codecs). If not set it will use the universal deserializer of the `Scheme` (or the
+	// object scheme when `Obj` is set). On dynamic webhooks (`Obj` not set) the objects that can't
+	// be decoded will fallback to `*unstructured.Unstructured`.
+	Decoder runtime.Decoder
+	// DisablePanicRecovery will disable the recovery of the validator panics. By default the panics are
+	// recovered and returned as review errors, so they are handled like any other validator error (e.g fail
+	// open webhooks will allow them). When disabled, the panics will not be converted to errors and will
+	// reach the HTTP handler (check `http.HandlerConfig.DisablePanicRecovery`).
+	DisablePanicRecovery bool
 }
 
 func (c *WebhookConfig) defaults() error {
@@ -37,7 +58,11 @@ func (c *WebhookConfig) defaults() error {
 	if c.Logger == nil {
 		c.Logger = log.Noop
 	}
-	c.Logger = c.Logger.WithValues(log.Kv{"webhook-id": c.ID, "webhhok-type": "validating"})
+	c.Logger = c.Logger.WithValues(log.Kv{"webhook-id": c.ID, "webhook-kind": "validating"})
+
+	if c.Tracer == nil {
+		c.Tracer = tracing.Noop
+	}
 
 	return nil
 }
@@ -53,9 +78,9 @@ func NewWebhook(cfg WebhookConfig) (webhook.Webhook, error) {
 	// infer the type.
 	var oc helpers.ObjectCreator
 	if cfg.Obj != nil {
-		oc = helpers.NewStaticObjectCreator(cfg.Obj)
+		oc = helpers.NewStaticObjectCreator(cfg.Obj, cfg.Decoder)
 	} else {
-		oc = helpers.NewDynamicObjectCreator()
+		oc = helpers.NewDynamicObjectCreator(cfg.Scheme, cfg.Decoder)
 	}
 
 	// Create our webhook and wrap for instrumentation (metrics and tracing).
@@ -65,6 +90,7 @@ func NewWebhook(cfg WebhookConfig) (webhook.Webhook, error) {
 		validator:     cfg.Validator,
 		cfg:           cfg,
 		logger:        cfg.Logger,
+		tracer:        cfg.Tracer,
 	}, nil
 }
 
@@ -74,13 +100,19 @@ type validatingWebhook struct {
 	validator     Validator
 	cfg           WebhookConfig
 	logger        log.Logger
+	tracer        tracing.Tracer
 }
 
 func (w validatingWebhook) ID() string { return w.id }
 
 func (w validatingWebhook) Kind() model.WebhookKind { return model.WebhookKindValidating }
 
-func (w validatingWebhook) Review(ctx context.Context, ar model.AdmissionReview) (model.AdmissionResponse, error) {
+func (w validatingWebhook) Review(ctx context.Context, ar model.AdmissionReview) (_ model.AdmissionResponse, err error) {
+	ctx = w.tracer.NewTrace(ctx, "validatingWebhook.Review")
+	defer func(ctx context.Context) { w.tracer.EndTrace(ctx, err) }(ctx)
+	w.tracer.SetValuesOnTrace(ctx, helpers.ReviewTraceValues(w.id, ar))
+	ctx = webhook.ContextWithID(ctx, w.id)
+
 	// Delete operations don't have body because should be gone on the deletion, instead they have the body
 	// of the object we want to delete as an old object.
 	raw := ar.NewObjectRaw
@@ -88,19 +120,39 @@ func (w validatingWebhook) Review(ctx context.Context, ar model.AdmissionReview)
 		raw = ar.OldObjectRaw
 	}
 
-	// Create a new object from the raw type.
-	runtimeObj, err := w.objectCreator.NewObject(raw)
+	// Subresources can have a different type from the webhook object (e.g `deployments/scale` is a `Scale`),
+	// we can't decode these into the webhook object type, so we allow them. The rest of the objects
+	// with a different type are not expected (e.g webhook misconfiguration).
+	if kind := helpers.ReviewObjectKind(ar, raw); w.cfg.Obj != nil && !helpers.IsKindOfObject(kind, w.cfg.Obj) {
+		if ar.SubResource != "" {
+			w.logger.WithCtxValues(ctx).Debugf("Subresource %q object type is not the webhook object type, ignoring validation", ar.SubResource)
+			return &model.ValidatingAdmissionResponse{ID: ar.ID, Allowed: true}, nil
+		}
+
+		return nil, helpers.NewUnexpectedKindError(kind, w.cfg.Obj)
+	}
+
+	dctx := w.tracer.NewTrace(ctx, "decode")
+	validatingObj, oldObj, err := w.decodeObjects(raw, ar.OldObjectRaw)
+	w.tracer.EndTrace(dctx, err)
 	if err != nil {
-		return nil, fmt.Errorf("could not create object from raw: %w", err)
+		return nil, err
 	}
 
-	validatingObj, ok := runtimeObj.(metav1.Object)
-	// Get the object.
-	if !ok {
-		return nil, fmt.Errorf("impossible to type assert the deep copy to metav1.Object")
+	// If we have an old object (e.g updates), make it available to the validators.
+	if oldObj != nil {
+		ctx = contextWithOldObject(ctx, oldObj)
 	}
 
-	res, err := w.validator.Validate(ctx, &ar, validatingObj)
+	// Don't validate if the review has been cancelled (e.g timeout).
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context done before validating: %w", err)
+	}
+
+	vctx := webhook.ContextWithReview(ctx, &ar)
+	vctx = w.tracer.NewTrace(vctx, "validate")
+	res, err := w.validate(vctx, &ar, validatingObj)
+	w.tracer.EndTrace(vctx, err)
 	if err != nil {
 		return nil, fmt.Errorf("validator error: %w", err)
 	}
@@ -109,13 +161,67 @@ func (w validatingWebhook) Review(ctx context.Context, ar model.AdmissionReview)
 		return nil, fmt.Errorf("result is required, validator result is nil")
 	}
 
+	// The validator could have ignored the context, the apiserver will not wait for us.
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context done after validating: %w", err)
+	}
+
 	w.logger.WithCtxValues(ctx).WithValues(log.Kv{"valid": res.Valid}).Debugf("Webhook validating review finished with %q result", res.Valid)
+	w.tracer.SetValuesOnTrace(ctx, map[string]interface{}{"allowed": res.Valid})
 
 	// Forge response.
 	return &model.ValidatingAdmissionResponse{
-		ID:       ar.ID,
-		Allowed:  res.Valid,
-		Message:  res.Message,
-		Warnings: res.Warnings,
+		ID:               ar.ID,
+		Allowed:          res.Valid,
+		Message:          res.Message,
+		StatusCode:       res.StatusCode,
+		Violations:       res.Violations,
+		Warnings:         res.Warnings,
+		AuditAnnotations: res.AuditAnnotations,
 	}, nil
 }
+
+// validate executes the validator, if enabled, recovering the validator panics and returning them as errors, this way
+// the panics will be handled like any other validator error (e.g fail open webhooks).
+func (w validatingWebhook) validate(ctx context.Context, ar *model.AdmissionReview, obj metav1.Object) (_ *ValidatorResult, err error) {
+	if !w.cfg.DisablePanicRecovery {
+		defer func() {
+			if r := recover(); r != nil {
+				w.logger.WithCtxValues(ctx).Errorf("validator panicked: %v\n%s", r, debug.Stack())
+				err = fmt.Errorf("validator panicked: %v", r)
+			}
+		}()
+	}
+
+	return w.validator.Validate(ctx, ar, obj)
+}
+
+// decodeObjects will create the object for the validation and the old object (if any) from the raw JSON data.
+func (w validatingWebhook) decodeObjects(raw, oldRaw []byte) (obj metav1.Object, oldObj metav1.Object, err error) {
+	// Create a new object from the raw type.
+	runtimeObj, err := w.objectCreator.NewObject(raw)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create object from raw: %w", err)
+	}
+
+	obj, err = helpers.ToK8sObj(runtimeObj)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid object: %w", err)
+	}
+
+	if len(oldRaw) == 0 {
+		return obj, nil, nil
+	}
+
+	oldRuntimeObj, err := w.objectCreator.NewObject(oldRaw)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create old object from raw: %w", err)
+	}
+
+	oldObj, err = helpers.ToK8sObj(oldRuntimeObj)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid old object: %w", err)
+	}
+
+	return obj, oldObj, nil
+}
@@ -4,15 +4,26 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	appsv1beta1 "k8s.io/api/apps/v1beta1"
+	authenticationv1 "k8s.io/api/authentication/v1"
 	corev1 "k8s.io/api/core/v1"
+	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 
+	"github.com/slok/kubewebhook/v2/pkg/log"
 	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/tracing"
+	"github.com/slok/kubewebhook/v2/pkg/webhook"
 	"github.com/slok/kubewebhook/v2/pkg/webhook/validating"
 )
 
@@ -43,6 +54,20 @@ func getFakeValidator(valid bool, message string) validating.Validator {
 	})
 }
 
+// labelDecoder is a custom decoder that sets a label on the decoded objects.
+type labelDecoder struct{}
+
+func (labelDecoder) Decode(data []byte, defaults *schema.GroupVersionKind, into runtime.Object) (runtime.Object, *schema.GroupVersionKind, error) {
+	obj, gvk, err := clientgoscheme.Codecs.UniversalDeserializer().Decode(data, defaults, into)
+	if err != nil {
+		return nil, nil, err
+	}
+	if mobj, ok := obj.(metav1.Object); ok {
+		mobj.SetLabels(map[string]string{"decoder": "custom"})
+	}
+	return obj, gvk, nil
+}
+
 func TestPodAdmissionReviewValidation(t *testing.T) {
 	tests := map[string]struct {
 		cfg         validating.WebhookConfig
@@ -64,6 +89,67 @@ func TestPodAdmissionReviewValidation(t *testing.T) {
 			expErr: true,
 		},
 
+		"A webhook review with a validator that panics should return an error.": {
+			cfg: validating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}},
+			validator: validating.ValidatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*validating.ValidatorResult, error) {
+				_ = obj.(*corev1.Service)
+				return &validating.ValidatorResult{Valid: true}, nil
+			}),
+			review: model.AdmissionReview{ID: "test", NewObjectRaw: getPodJSON()},
+			expErr: true,
+		},
+
+		"A validator should have the webhook ID on the context.": {
+			cfg: validating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}},
+			validator: validating.ValidatorFunc(func(ctx context.Context, _ *model.AdmissionReview, _ metav1.Object) (*validating.ValidatorResult, error) {
+				return &validating.ValidatorResult{Valid: false, Message: webhook.IDFromContext(ctx)}, nil
+			}),
+			review: model.AdmissionReview{ID: "test", NewObjectRaw: getPodJSON()},
+			expResponse: &model.ValidatingAdmissionResponse{
+				ID:      "test",
+				Allowed: false,
+				Message: "test",
+			},
+		},
+
+		"A validator should have the admission review on the context.": {
+			cfg: validating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}},
+			validator: validating.ValidatorFunc(func(ctx context.Context, ar *model.AdmissionReview, _ metav1.Object) (*validating.ValidatorResult, error) {
+				ctxAR := webhook.ReviewFromContext(ctx)
+				return &validating.ValidatorResult{Valid: false, Message: fmt.Sprintf("%t %s %t", ctxAR == ar, ctxAR.SubResource, ctxAR.DryRun)}, nil
+			}),
+			review: model.AdmissionReview{ID: "test", SubResource: "status", DryRun: true, NewObjectRaw: getPodJSON()},
+			expResponse: &model.ValidatingAdmissionResponse{
+				ID:      "test",
+				Allowed: false,
+				Message: "true status true",
+			},
+		},
+
+		"A validator should have the user info of the review.": {
+			cfg: validating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}},
+			validator: validating.ValidatorFunc(func(_ context.Context, ar *model.AdmissionReview, _ metav1.Object) (*validating.ValidatorResult, error) {
+				u := ar.UserInfo
+				msg := fmt.Sprintf("%s %s %v %v", u.Username, u.UID, u.Groups, u.Extra)
+				return &validating.ValidatorResult{Valid: false, Message: msg}, nil
+			}),
+			review: model.AdmissionReview{
+				ID:           "test",
+				NewObjectRaw: getPodJSON(),
+				UserInfo: authenticationv1.UserInfo{
+					Username: "user1",
+					UID:      "1234",
+					Groups:   []string{"group1", "group2"},
+					Extra:    map[string]authenticationv1.ExtraValue{"k1": {"v1"}},
+				},
+			},
+			expResponse: &model.ValidatingAdmissionResponse{
+				ID:      "test",
+				Allowed: false,
+				Message: "user1 1234 [group1 group2] map[k1:[v1]]",
+			},
+		},
+
 		"A static webhook review of a Pod with a valid validator result should return allowed.": {
 			cfg:       validating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}},
 			validator: getFakeValidator(true, ""),
@@ -111,6 +197,26 @@ func TestPodAdmissionReviewValidation(t *testing.T) {
 			},
 		},
 
+		"A static webhook review of a delete operation on a protected Pod should deny.": {
+			cfg: validating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}},
+			validator: validating.ValidatorFunc(func(_ context.Context, ar *model.AdmissionReview, obj metav1.Object) (*validating.ValidatorResult, error) {
+				if ar.Operation == model.OperationDelete && obj.GetLabels()["protected"] == "true" {
+					return &validating.ValidatorResult{Valid: false, Message: "protected resources can't be deleted"}, nil
+				}
+				return &validating.ValidatorResult{Valid: true}, nil
+			}),
+			review: model.AdmissionReview{
+				ID:           "test",
+				Operation:    model.OperationDelete,
+				OldObjectRaw: []byte(`{"kind":"Pod","apiVersion":"v1","metadata":{"name":"testPod","labels":{"protected":"true"}}}`),
+			},
+			expResponse: &model.ValidatingAdmissionResponse{
+				ID:      "test",
+				Allowed: false,
+				Message: "protected resources can't be deleted",
+			},
+		},
+
 		"A dynamic webhook review of a Pod with a valid validator result should return allowed.": {
 			cfg:       validating.WebhookConfig{ID: "test"},
 			validator: getFakeValidator(true, ""),
@@ -168,6 +274,179 @@ func TestPodAdmissionReviewValidation(t *testing.T) {
 			},
 		},
 
+		"A dynamic webhook review of a legacy extensions/v1beta1 Ingress should receive the typed object.": {
+			cfg: validating.WebhookConfig{ID: "test"},
+			validator: validating.ValidatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*validating.ValidatorResult, error) {
+				_, ok := obj.(*extensionsv1beta1.Ingress)
+				return &validating.ValidatorResult{Valid: ok}, nil
+			}),
+			review: model.AdmissionReview{
+				ID:           "test",
+				NewObjectRaw: []byte(`{"kind":"Ingress","apiVersion":"extensions/v1beta1","metadata":{"name":"test","namespace":"myNS"}}`),
+			},
+			expResponse: &model.ValidatingAdmissionResponse{
+				ID:      "test",
+				Allowed: true,
+			},
+		},
+
+		"A dynamic webhook review of a legacy apps/v1beta1 Deployment should receive the typed object.": {
+			cfg: validating.WebhookConfig{ID: "test"},
+			validator: validating.ValidatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*validating.ValidatorResult, error) {
+				_, ok := obj.(*appsv1beta1.Deployment)
+				return &validating.ValidatorResult{Valid: ok}, nil
+			}),
+			review: model.AdmissionReview{
+				ID:           "test",
+				NewObjectRaw: []byte(`{"kind":"Deployment","apiVersion":"apps/v1beta1","metadata":{"name":"test","namespace":"myNS"}}`),
+			},
+			expResponse: &model.ValidatingAdmissionResponse{
+				ID:      "test",
+				Allowed: true,
+			},
+		},
+
+		"A static webhook review of an update operation should have the old object available to the validator.": {
+			cfg: validating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}},
+			validator: validating.ValidatorFunc(func(ctx context.Context, _ *model.AdmissionReview, obj metav1.Object) (*validating.ValidatorResult, error) {
+				oldPod, ok := validating.OldObjectFromContext(ctx).(*corev1.Pod)
+				if !ok {
+					return nil, fmt.Errorf("old object is not a pod")
+				}
+
+				// Labels are immutable.
+				valid := oldPod.Labels["test1"] == obj.GetLabels()["test1"]
+				return &validating.ValidatorResult{Valid: valid, Message: "labels are immutable"}, nil
+			}),
+			review: model.AdmissionReview{
+				ID:           "test",
+				Operation:    model.OperationUpdate,
+				OldObjectRaw: []byte(`{"kind":"Pod","apiVersion":"v1","metadata":{"name":"testPod","labels":{"test1":"oldValue1"}}}`),
+				NewObjectRaw: getPodJSON(),
+			},
+			expResponse: &model.ValidatingAdmissionResponse{
+				ID:      "test",
+				Allowed: false,
+				Message: "labels are immutable",
+			},
+		},
+
+		"A static webhook review that denies with a custom status code should return it on the response.": {
+			cfg: validating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}},
+			validator: validating.ValidatorFunc(func(_ context.Context, _ *model.AdmissionReview, _ metav1.Object) (*validating.ValidatorResult, error) {
+				return &validating.ValidatorResult{Valid: false, Message: "privileged pods are forbidden", StatusCode: 403}, nil
+			}),
+			review: model.AdmissionReview{
+				ID:           "test",
+				Operation:    model.OperationCreate,
+				NewObjectRaw: getPodJSON(),
+			},
+			expResponse: &model.ValidatingAdmissionResponse{
+				ID:         "test",
+				Allowed:    false,
+				Message:    "privileged pods are forbidden",
+				StatusCode: 403,
+			},
+		},
+
+		"A static webhook review that denies with violations should return them on the response.": {
+			cfg: validating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}},
+			validator: validating.ValidatorFunc(func(_ context.Context, _ *model.AdmissionReview, _ metav1.Object) (*validating.ValidatorResult, error) {
+				return &validating.ValidatorResult{Valid: false, Violations: []model.Violation{
+					{Field: "spec.hostNetwork", Message: "host network is forbidden"},
+					{Field: "spec.hostPID", Message: "host PID is forbidden"},
+				}}, nil
+			}),
+			review: model.AdmissionReview{
+				ID:           "test",
+				Operation:    model.OperationCreate,
+				NewObjectRaw: getPodJSON(),
+			},
+			expResponse: &model.ValidatingAdmissionResponse{
+				ID:      "test",
+				Allowed: false,
+				Violations: []model.Violation{
+					{Field: "spec.hostNetwork", Message: "host network is forbidden"},
+					{Field: "spec.hostPID", Message: "host PID is forbidden"},
+				},
+			},
+		},
+
+		"A static webhook review of a subresource with a different type of the webhook should be allowed without validating.": {
+			cfg: validating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}},
+			validator: validating.ValidatorFunc(func(_ context.Context, _ *model.AdmissionReview, _ metav1.Object) (*validating.ValidatorResult, error) {
+				return nil, fmt.Errorf("should not be called")
+			}),
+			review: model.AdmissionReview{
+				ID:           "test",
+				Operation:    model.OperationCreate,
+				SubResource:  "eviction",
+				NewObjectRaw: []byte(`{"kind":"Eviction","apiVersion":"policy/v1beta1","metadata":{"name":"testPod","namespace":"myNS"}}`),
+			},
+			expResponse: &model.ValidatingAdmissionResponse{
+				ID:      "test",
+				Allowed: true,
+			},
+		},
+
+		"A static webhook review of an object with a different type of the webhook should fail.": {
+			cfg: validating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}},
+			validator: validating.ValidatorFunc(func(_ context.Context, _ *model.AdmissionReview, _ metav1.Object) (*validating.ValidatorResult, error) {
+				return nil, fmt.Errorf("should not be called")
+			}),
+			review: model.AdmissionReview{
+				ID:           "test",
+				Operation:    model.OperationCreate,
+				NewObjectRaw: []byte(`{"kind":"Service","apiVersion":"v1","metadata":{"name":"testSvc","namespace":"myNS"}}`),
+			},
+			expErr: true,
+		},
+
+		"A static webhook review of an object without type and a different review kind of the webhook should fail.": {
+			cfg: validating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}},
+			validator: validating.ValidatorFunc(func(_ context.Context, _ *model.AdmissionReview, _ metav1.Object) (*validating.ValidatorResult, error) {
+				return nil, fmt.Errorf("should not be called")
+			}),
+			review: model.AdmissionReview{
+				ID:           "test",
+				Operation:    model.OperationCreate,
+				RequestGVK:   &metav1.GroupVersionKind{Version: "v1", Kind: "Service"},
+				NewObjectRaw: []byte(`{"metadata":{"name":"testSvc","namespace":"myNS"}}`),
+			},
+			expErr: true,
+		},
+
+		"A static webhook review of an object without type and the same review kind of the webhook should be validated.": {
+			cfg:       validating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}},
+			validator: getFakeValidator(true, ""),
+			review: model.AdmissionReview{
+				ID:           "test",
+				Operation:    model.OperationCreate,
+				RequestGVK:   &metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+				NewObjectRaw: []byte(`{"metadata":{"name":"testPod","namespace":"myNS"}}`),
+			},
+			expResponse: &model.ValidatingAdmissionResponse{
+				ID:      "test",
+				Allowed: true,
+			},
+		},
+
+		"A static webhook review of a create operation should not have the old object available to the validator.": {
+			cfg: validating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}},
+			validator: validating.ValidatorFunc(func(ctx context.Context, _ *model.AdmissionReview, obj metav1.Object) (*validating.ValidatorResult, error) {
+				return &validating.ValidatorResult{Valid: validating.OldObjectFromContext(ctx) == nil}, nil
+			}),
+			review: model.AdmissionReview{
+				ID:           "test",
+				Operation:    model.OperationCreate,
+				NewObjectRaw: getPodJSON(),
+			},
+			expResponse: &model.ValidatingAdmissionResponse{
+				ID:      "test",
+				Allowed: true,
+			},
+		},
+
 		"A dynamic webhook review of a delete operation on a unknown type should check that a label is present.": {
 			cfg: validating.WebhookConfig{ID: "test"},
 			validator: validating.ValidatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*validating.ValidatorResult, error) {
@@ -204,6 +483,32 @@ func TestPodAdmissionReviewValidation(t *testing.T) {
 				Allowed: true,
 			},
 		},
+
+		"A static webhook with a custom decoder should use the decoder to decode the objects.": {
+			cfg: validating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}, Decoder: labelDecoder{}},
+			validator: validating.ValidatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*validating.ValidatorResult, error) {
+				_, ok := obj.(*corev1.Pod)
+				return &validating.ValidatorResult{Valid: ok && obj.GetLabels()["decoder"] == "custom"}, nil
+			}),
+			review: model.AdmissionReview{ID: "test", NewObjectRaw: getPodJSON()},
+			expResponse: &model.ValidatingAdmissionResponse{
+				ID:      "test",
+				Allowed: true,
+			},
+		},
+
+		"A dynamic webhook with a custom decoder should use the decoder to decode the objects.": {
+			cfg: validating.WebhookConfig{ID: "test", Decoder: labelDecoder{}},
+			validator: validating.ValidatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*validating.ValidatorResult, error) {
+				_, ok := obj.(*corev1.Pod)
+				return &validating.ValidatorResult{Valid: ok && obj.GetLabels()["decoder"] == "custom"}, nil
+			}),
+			review: model.AdmissionReview{ID: "test", NewObjectRaw: getPodJSON()},
+			expResponse: &model.ValidatingAdmissionResponse{
+				ID:      "test",
+				Allowed: true,
+			},
+		},
 	}
 
 	for name, test := range tests {
@@ -228,3 +533,275 @@ func TestPodAdmissionReviewValidation(t *testing.T) {
 		})
 	}
 }
+
+// valuesTracer is a tracer that records the values set on the traces.
+type valuesTracer struct {
+	values map[string]interface{}
+}
+
+var _ tracing.Tracer = &valuesTracer{}
+
+func (v *valuesTracer) NewTrace(ctx context.Context, _ string) context.Context { return ctx }
+func (v *valuesTracer) NewHTTPTrace(r *http.Request, _ string) context.Context {
+	return r.Context()
+}
+func (v *valuesTracer) SetValuesOnTrace(_ context.Context, values map[string]interface{}) {
+	for k, val := range values {
+		v.values[k] = val
+	}
+}
+func (v *valuesTracer) EndTrace(_ context.Context, _ error) {}
+
+func TestValidatingWebhookTraceValues(t *testing.T) {
+	tests := map[string]struct {
+		valid     bool
+		expValues map[string]interface{}
+	}{
+		"A valid review should set the review values and the allowed result on the trace.": {
+			valid: true,
+			expValues: map[string]interface{}{
+				"webhook-id": "test",
+				"request-id": "test-uid",
+				"op":         "create",
+				"kind":       "v1/Pod",
+				"ns":         "default",
+				"name":       "testPod",
+				"dry-run":    false,
+				"allowed":    true,
+			},
+		},
+
+		"A not valid review should set the review values and the not allowed result on the trace.": {
+			valid: false,
+			expValues: map[string]interface{}{
+				"webhook-id": "test",
+				"request-id": "test-uid",
+				"op":         "create",
+				"kind":       "v1/Pod",
+				"ns":         "default",
+				"name":       "testPod",
+				"dry-run":    false,
+				"allowed":    false,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			tracer := &valuesTracer{values: map[string]interface{}{}}
+			wh, err := validating.NewWebhook(validating.WebhookConfig{
+				ID:     "test",
+				Obj:    &corev1.Pod{},
+				Tracer: tracer,
+				Validator: validating.ValidatorFunc(func(_ context.Context, _ *model.AdmissionReview, _ metav1.Object) (*validating.ValidatorResult, error) {
+					return &validating.ValidatorResult{Valid: test.valid}, nil
+				}),
+			})
+			require.NoError(err)
+
+			_, err = wh.Review(context.TODO(), model.AdmissionReview{
+				ID:           "test-uid",
+				Name:         "testPod",
+				Namespace:    "default",
+				Operation:    model.OperationCreate,
+				RequestGVK:   &metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+				NewObjectRaw: getPodJSON(),
+			})
+			require.NoError(err)
+
+			assert.Equal(t, test.expValues, tracer.values)
+		})
+	}
+}
+
+func TestNewWebhookConfig(t *testing.T) {
+	validator := validating.ValidatorFunc(func(_ context.Context, _ *model.AdmissionReview, _ metav1.Object) (*validating.ValidatorResult, error) {
+		return &validating.ValidatorResult{Valid: true}, nil
+	})
+
+	tests := map[string]struct {
+		cfg    validating.WebhookConfig
+		expErr bool
+	}{
+		"A configuration without ID should fail.": {
+			cfg:    validating.WebhookConfig{Validator: validator},
+			expErr: true,
+		},
+
+		"A configuration without validator should fail.": {
+			cfg:    validating.WebhookConfig{ID: "test"},
+			expErr: true,
+		},
+
+		"A configuration with the required fields should not fail.": {
+			cfg: validating.WebhookConfig{ID: "test", Validator: validator},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			wh, err := validating.NewWebhook(test.cfg)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal("test", wh.ID())
+				assert.Equal(model.WebhookKind(model.WebhookKindValidating), wh.Kind())
+			}
+		})
+	}
+}
+
+func TestFailOpenWebhookValidatorPanic(t *testing.T) {
+	tests := map[string]struct {
+		cfg         validating.WebhookConfig
+		expResponse model.AdmissionResponse
+		expPanic    bool
+	}{
+		"A fail open webhook with a validator that panics should allow the resource by default.": {
+			cfg:         validating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}},
+			expResponse: &model.ValidatingAdmissionResponse{ID: "test", Allowed: true},
+		},
+
+		"A fail open webhook with a validator that panics should panic when the panic recovery is disabled.": {
+			cfg:      validating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}, DisablePanicRecovery: true},
+			expPanic: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			test.cfg.Validator = validating.ValidatorFunc(func(_ context.Context, _ *model.AdmissionReview, _ metav1.Object) (*validating.ValidatorResult, error) {
+				panic("wanted panic")
+			})
+			wh, err := validating.NewWebhook(test.cfg)
+			require.NoError(err)
+			wh = webhook.NewFailOpenWebhook(log.Noop, wh)
+
+			review := model.AdmissionReview{ID: "test", NewObjectRaw: getPodJSON()}
+			if test.expPanic {
+				assert.Panics(func() { _, _ = wh.Review(context.TODO(), review) })
+				return
+			}
+
+			gotResponse, err := wh.Review(context.TODO(), review)
+			if assert.NoError(err) {
+				assert.Equal(test.expResponse, gotResponse)
+			}
+		})
+	}
+}
+
+func TestUnstructuredAdmissionReviewValidation(t *testing.T) {
+	tests := map[string]struct {
+		obj         []byte
+		expResponse model.AdmissionResponse
+	}{
+		"An unstructured webhook should validate any kind.": {
+			obj:         []byte(`{"kind":"Foo","apiVersion":"example.io/v1","metadata":{"name":"test"}}`),
+			expResponse: &model.ValidatingAdmissionResponse{ID: "test", Allowed: true, Message: "test"},
+		},
+
+		"An unstructured webhook should validate any kind (invalid).": {
+			obj:         []byte(`{"kind":"Bar","apiVersion":"example.io/v1","metadata":{"name":"invalid"}}`),
+			expResponse: &model.ValidatingAdmissionResponse{ID: "test", Allowed: false, Message: "invalid"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			wh, err := validating.NewWebhook(validating.WebhookConfig{
+				ID:  "test",
+				Obj: &unstructured.Unstructured{},
+				Validator: validating.ValidatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*validating.ValidatorResult, error) {
+					return &validating.ValidatorResult{Valid: obj.GetName() != "invalid", Message: obj.GetName()}, nil
+				}),
+			})
+			require.NoError(err)
+
+			gotResponse, err := wh.Review(context.TODO(), model.AdmissionReview{ID: "test", NewObjectRaw: test.obj})
+			if assert.NoError(err) {
+				assert.Equal(test.expResponse, gotResponse)
+			}
+		})
+	}
+}
+
+func TestPodAdmissionReviewContext(t *testing.T) {
+	tests := map[string]struct {
+		ctx       func() (context.Context, context.CancelFunc)
+		validator func(cancel context.CancelFunc) validating.Validator
+		expErr    bool
+	}{
+		"A validator should receive the context deadline.": {
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 5*time.Second)
+			},
+			validator: func(_ context.CancelFunc) validating.Validator {
+				return validating.ValidatorFunc(func(ctx context.Context, _ *model.AdmissionReview, _ metav1.Object) (*validating.ValidatorResult, error) {
+					if _, ok := ctx.Deadline(); !ok {
+						return nil, fmt.Errorf("context without deadline")
+					}
+					return &validating.ValidatorResult{Valid: true}, nil
+				})
+			},
+		},
+
+		"An already cancelled context should fail without validating.": {
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx, cancel
+			},
+			validator: func(_ context.CancelFunc) validating.Validator {
+				return validating.ValidatorFunc(func(_ context.Context, _ *model.AdmissionReview, _ metav1.Object) (*validating.ValidatorResult, error) {
+					return nil, fmt.Errorf("should not be called")
+				})
+			},
+			expErr: true,
+		},
+
+		"A context cancelled while validating should fail.": {
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithCancel(context.Background())
+			},
+			validator: func(cancel context.CancelFunc) validating.Validator {
+				return validating.ValidatorFunc(func(_ context.Context, _ *model.AdmissionReview, _ metav1.Object) (*validating.ValidatorResult, error) {
+					cancel()
+					return &validating.ValidatorResult{Valid: true}, nil
+				})
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			ctx, cancel := test.ctx()
+			defer cancel()
+
+			wh, err := validating.NewWebhook(validating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}, Validator: test.validator(cancel)})
+			assert.NoError(err)
+
+			gotResponse, err := wh.Review(ctx, model.AdmissionReview{ID: "test", NewObjectRaw: getPodJSON()})
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(&model.ValidatingAdmissionResponse{ID: "test", Allowed: true}, gotResponse)
+			}
+		})
+	}
+}
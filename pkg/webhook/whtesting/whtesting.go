@@ -0,0 +1,128 @@
+// Package whtesting has helpers to test the webhooks, mutators and validators using
+// Kubernetes objects instead of crafting the admission reviews by hand.
+package whtesting
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/webhook"
+)
+
+// TestReviewID is the ID of the admission reviews created by the helpers.
+const TestReviewID = "kubewebhook-test-review"
+
+// NewAdmissionReview returns a new `v1` admission review for the object and operation.
+// The object will be set as the new object of the review, on delete operations it will
+// be set as the old object.
+func NewAdmissionReview(obj runtime.Object, op model.AdmissionReviewOp) (model.AdmissionReview, error) {
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return model.AdmissionReview{}, fmt.Errorf("could not marshal object: %w", err)
+	}
+
+	ar := model.AdmissionReview{
+		ID:        TestReviewID,
+		Operation: op,
+		Version:   model.AdmissionReviewVersionV1,
+	}
+
+	if op == model.OperationDelete {
+		ar.OldObjectRaw = raw
+	} else {
+		ar.NewObjectRaw = raw
+	}
+
+	if gvk := obj.GetObjectKind().GroupVersionKind(); !gvk.Empty() {
+		ar.RequestGVK = &metav1.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind}
+	}
+
+	if mobj, ok := obj.(metav1.Object); ok {
+		ar.Name = mobj.GetName()
+		ar.Namespace = mobj.GetNamespace()
+	}
+
+	return ar, nil
+}
+
+// NewUpdateAdmissionReview returns a new `v1` update admission review with the old object
+// and the new object (the one being updated).
+func NewUpdateAdmissionReview(oldObj, newObj runtime.Object) (model.AdmissionReview, error) {
+	ar, err := NewAdmissionReview(newObj, model.OperationUpdate)
+	if err != nil {
+		return model.AdmissionReview{}, err
+	}
+
+	ar.OldObjectRaw, err = json.Marshal(oldObj)
+	if err != nil {
+		return model.AdmissionReview{}, fmt.Errorf("could not marshal old object: %w", err)
+	}
+
+	return ar, nil
+}
+
+// ApplyResponse applies the mutating webhook response JSON patch on the object and
+// returns the mutated object as a new object of the same type. The received object
+// is not modified.
+func ApplyResponse(obj runtime.Object, resp model.AdmissionResponse) (runtime.Object, error) {
+	mresp, ok := resp.(*model.MutatingAdmissionResponse)
+	if !ok {
+		return nil, fmt.Errorf("response is not a mutating admission response")
+	}
+
+	// Nothing to apply.
+	if len(mresp.JSONPatchPatch) == 0 {
+		return obj.DeepCopyObject(), nil
+	}
+
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal object: %w", err)
+	}
+
+	patch, err := jsonpatch.DecodePatch(mresp.JSONPatchPatch)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode JSON patch: %w", err)
+	}
+
+	mutatedRaw, err := patch.Apply(raw)
+	if err != nil {
+		return nil, fmt.Errorf("could not apply JSON patch: %w", err)
+	}
+
+	// Create a new object of the same type and fill it with the mutated data.
+	mutatedObj, ok := reflect.New(reflect.Indirect(reflect.ValueOf(obj)).Type()).Interface().(runtime.Object)
+	if !ok {
+		return nil, fmt.Errorf("could not create a new object of the object type")
+	}
+
+	err = json.Unmarshal(mutatedRaw, mutatedObj)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal mutated object: %w", err)
+	}
+
+	return mutatedObj, nil
+}
+
+// MutateAndApply reviews the object as a create operation using the mutating webhook and
+// returns the mutated object after applying the webhook response.
+func MutateAndApply(wh webhook.Webhook, obj runtime.Object) (runtime.Object, error) {
+	ar, err := NewAdmissionReview(obj, model.OperationCreate)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := wh.Review(context.Background(), ar)
+	if err != nil {
+		return nil, fmt.Errorf("webhook review failed: %w", err)
+	}
+
+	return ApplyResponse(obj, resp)
+}
@@ -0,0 +1,149 @@
+package whtesting_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/slok/kubewebhook/v2/pkg/model"
+	"github.com/slok/kubewebhook/v2/pkg/webhook/mutating"
+	"github.com/slok/kubewebhook/v2/pkg/webhook/whtesting"
+)
+
+func getTestPod() *corev1.Pod {
+	return &corev1.Pod{
+		TypeMeta: metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test-ns",
+			Labels:    map[string]string{"k1": "v1"},
+		},
+	}
+}
+
+func TestNewAdmissionReview(t *testing.T) {
+	podRaw := []byte(`{"kind":"Pod","apiVersion":"v1","metadata":{"name":"test","namespace":"test-ns","creationTimestamp":null,"labels":{"k1":"v1"}},"spec":{"containers":null},"status":{}}`)
+
+	tests := map[string]struct {
+		op        model.AdmissionReviewOp
+		expReview model.AdmissionReview
+	}{
+		"A create operation should set the object as the new object.": {
+			op: model.OperationCreate,
+			expReview: model.AdmissionReview{
+				ID:           whtesting.TestReviewID,
+				Name:         "test",
+				Namespace:    "test-ns",
+				Operation:    model.OperationCreate,
+				Version:      model.AdmissionReviewVersionV1,
+				RequestGVK:   &metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+				NewObjectRaw: podRaw,
+			},
+		},
+
+		"A delete operation should set the object as the old object.": {
+			op: model.OperationDelete,
+			expReview: model.AdmissionReview{
+				ID:           whtesting.TestReviewID,
+				Name:         "test",
+				Namespace:    "test-ns",
+				Operation:    model.OperationDelete,
+				Version:      model.AdmissionReviewVersionV1,
+				RequestGVK:   &metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+				OldObjectRaw: podRaw,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			gotReview, err := whtesting.NewAdmissionReview(getTestPod(), test.op)
+			if assert.NoError(err) {
+				assert.Equal(test.expReview, gotReview)
+			}
+		})
+	}
+}
+
+func TestNewUpdateAdmissionReview(t *testing.T) {
+	assert := assert.New(t)
+
+	oldPod := getTestPod()
+	newPod := getTestPod()
+	newPod.Labels["k2"] = "v2"
+
+	gotReview, err := whtesting.NewUpdateAdmissionReview(oldPod, newPod)
+	if assert.NoError(err) {
+		expReview := model.AdmissionReview{
+			ID:           whtesting.TestReviewID,
+			Name:         "test",
+			Namespace:    "test-ns",
+			Operation:    model.OperationUpdate,
+			Version:      model.AdmissionReviewVersionV1,
+			RequestGVK:   &metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+			OldObjectRaw: []byte(`{"kind":"Pod","apiVersion":"v1","metadata":{"name":"test","namespace":"test-ns","creationTimestamp":null,"labels":{"k1":"v1"}},"spec":{"containers":null},"status":{}}`),
+			NewObjectRaw: []byte(`{"kind":"Pod","apiVersion":"v1","metadata":{"name":"test","namespace":"test-ns","creationTimestamp":null,"labels":{"k1":"v1","k2":"v2"}},"spec":{"containers":null},"status":{}}`),
+		}
+		assert.Equal(expReview, gotReview)
+	}
+}
+
+func TestMutateAndApply(t *testing.T) {
+	tests := map[string]struct {
+		mutator mutating.Mutator
+		expObj  runtime.Object
+		expErr  bool
+	}{
+		"A mutation should be applied on the object.": {
+			mutator: mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, obj metav1.Object) (*mutating.MutatorResult, error) {
+				obj.SetLabels(map[string]string{"k1": "v1", "k2": "v2"})
+				return &mutating.MutatorResult{MutatedObject: obj}, nil
+			}),
+			expObj: func() runtime.Object {
+				p := getTestPod()
+				p.Labels["k2"] = "v2"
+				return p
+			}(),
+		},
+
+		"A no mutation should return the same object.": {
+			mutator: mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, _ metav1.Object) (*mutating.MutatorResult, error) {
+				return &mutating.MutatorResult{NoMutation: true}, nil
+			}),
+			expObj: getTestPod(),
+		},
+
+		"A failed mutation should fail.": {
+			mutator: mutating.MutatorFunc(func(_ context.Context, _ *model.AdmissionReview, _ metav1.Object) (*mutating.MutatorResult, error) {
+				return nil, fmt.Errorf("something")
+			}),
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			wh, err := mutating.NewWebhook(mutating.WebhookConfig{ID: "test", Obj: &corev1.Pod{}, Mutator: test.mutator})
+			assert.NoError(err)
+
+			pod := getTestPod()
+			gotObj, err := whtesting.MutateAndApply(wh, pod)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expObj, gotObj)
+				assert.Equal(getTestPod(), pod) // The original object should not be modified.
+			}
+		})
+	}
+}